@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+	"github.com/dhamidi/proompt/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+// mountBundle mounts the bundle archive at its absolute path in fs, which it
+// returns. Bundles with a missing or invalid manifest or prompts that do not
+// match their checksums are not mounted
+func mountBundle(fs *filesystem.MountFS, bundle string) (string, error) {
+	path, err := filepath.Abs(bundle)
+	if err != nil {
+		return "", err
+	}
+	archive, err := filesystem.OpenArchive(path)
+	if err != nil {
+		return "", err
+	}
+	if _, _, err := prompt.ReadBundle(archive); err != nil {
+		return "", err
+	}
+	fs.Mount(path, archive)
+	return path, nil
+}
+
+// packCmd creates the pack command
+func packCmd(manager prompt.Manager, fs filesystem.Filesystem) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pack <bundle> [name...]",
+		Short: "Pack prompts into a bundle archive",
+		Long:  "Write prompts to a .tar.gz, .tgz or .zip bundle with a manifest of their names, metadata and checksums. Without names all prompts are packed; a name ending in / packs a folder. By default the prompts in use are packed; --from packs the prompts of a single level instead.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, _ := cmd.Flags().GetString("from")
+			force, _ := cmd.Flags().GetBool("force")
+
+			output := args[0]
+			format := filesystem.ArchiveFormat(output)
+			if format == "" {
+				return fmt.Errorf("%w: %s", filesystem.ErrUnknownArchive, output)
+			}
+			if _, err := fs.Stat(output); err == nil && !force {
+				return fmt.Errorf("%s already exists; use --force to replace it", output)
+			}
+
+			prompts, err := selectPrompts(manager, from, args[1:])
+			if err != nil {
+				return err
+			}
+
+			var archive bytes.Buffer
+			manifest, err := prompt.WriteBundle(&archive, format, prompts, time.Now())
+			if err != nil {
+				return fmt.Errorf("failed to pack prompts: %w", err)
+			}
+			if err := fs.WriteFile(output, archive.Bytes(), 0644); err != nil {
+				return fmt.Errorf("failed to write bundle: %w", err)
+			}
+
+			for _, entry := range manifest.Prompts {
+				fmt.Printf("  %s (%s)\n", entry.Name, entry.Source)
+			}
+			fmt.Printf("Packed %d prompt(s) into %s\n", len(manifest.Prompts), output)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("from", "", "Pack the prompts of one level: directory, project, project-local or user")
+	cmd.Flags().Bool("force", false, "Replace the bundle if it exists")
+
+	return cmd
+}
+
+// selectPrompts returns the prompts to pack: those of the level from, or the
+// prompts in use if from is empty, limited to names if any are given
+func selectPrompts(manager prompt.Manager, from string, names []string) ([]prompt.PromptInfo, error) {
+	var prompts []prompt.PromptInfo
+	var err error
+	if from == "" {
+		prompts, err = manager.List()
+	} else {
+		prompts, err = manager.ListAll()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompts: %w", err)
+	}
+
+	var selected []prompt.PromptInfo
+	matched := make(map[string]bool, len(names))
+	for _, info := range prompts {
+		if from != "" && info.Source != from {
+			continue
+		}
+		if len(names) == 0 {
+			selected = append(selected, info)
+			continue
+		}
+		for _, name := range names {
+			if info.Name == name || (strings.HasSuffix(name, "/") && strings.HasPrefix(info.Name, name)) {
+				selected = append(selected, info)
+				matched[name] = true
+				break
+			}
+		}
+	}
+
+	for _, name := range names {
+		if !matched[name] {
+			return nil, fmt.Errorf("prompt '%s' not found", name)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no prompts to pack")
+	}
+	return selected, nil
+}
+
+// unpackCmd creates the unpack command
+func unpackCmd(manager prompt.Manager, history *prompt.History) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unpack <bundle> --to <level>",
+		Short: "Install the prompts of a bundle into a level",
+		Long:  "Copy the prompts of a .tar.gz, .tgz or .zip bundle created by pack into a level of the hierarchy (directory, project, project-local, user). Checksums are verified before anything is written. With --conflict refuse, nothing is installed if any prompt already exists at the level.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target, _ := cmd.Flags().GetString("to")
+			conflict, _ := cmd.Flags().GetString("conflict")
+			if err := validateConflict(conflict); err != nil {
+				return err
+			}
+
+			archive, err := filesystem.OpenArchive(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open bundle: %w", err)
+			}
+			_, prompts, err := prompt.ReadBundle(archive)
+			if err != nil {
+				return fmt.Errorf("failed to read bundle %s: %w", args[0], err)
+			}
+
+			if conflict == CONFLICT_REFUSE {
+				var existing []string
+				for _, info := range prompts {
+					if _, err := manager.GetAt(info.Name, target); err == nil {
+						existing = append(existing, info.Name)
+					}
+				}
+				if len(existing) > 0 {
+					return fmt.Errorf("prompts already exist at the %s level: %s; use --conflict overwrite or --conflict merge", target, strings.Join(existing, ", "))
+				}
+			}
+
+			for i := range prompts {
+				if _, err := storePrompt(manager, history, &prompts[i], target, conflict, "unpack"); err != nil {
+					return err
+				}
+				fmt.Printf("  %s\n", prompts[i].Name)
+			}
+			fmt.Printf("Unpacked %d prompt(s) from %s into %s\n", len(prompts), args[0], target)
+			return nil
+		},
+	}
+
+	addTransferFlags(cmd)
+
+	return cmd
+}
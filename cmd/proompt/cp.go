@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dhamidi/proompt/pkg/diff"
+	"github.com/dhamidi/proompt/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+// How mv and cp handle a prompt of the same name at the target level
+const (
+	CONFLICT_REFUSE    = "refuse"    // fail and leave both prompts alone
+	CONFLICT_OVERWRITE = "overwrite" // replace the prompt at the target level
+	CONFLICT_MERGE     = "merge"     // three-way merge, leaving conflict markers where both differ
+)
+
+// cpCmd creates the copy command
+func cpCmd(manager prompt.Manager, history *prompt.History) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cp <name> --to <level>",
+		Short: "Copy a prompt to another level",
+		Long:  "Copy a prompt to another level of the hierarchy (directory, project, project-local, user). The name may be source-qualified, e.g. project-local:review.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target, _ := cmd.Flags().GetString("to")
+			conflict, _ := cmd.Flags().GetString("conflict")
+
+			source, copied, err := transferPrompt(manager, history, args[0], target, conflict, "cp")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Copied prompt: %s (%s -> %s)\n", source.Name, source.Source, copied.Source)
+			return printShadowing(manager, source.Name)
+		},
+	}
+
+	addTransferFlags(cmd)
+
+	return cmd
+}
+
+// addTransferFlags adds the flags shared by mv and cp
+func addTransferFlags(cmd *cobra.Command) {
+	cmd.Flags().String("to", "", "Target level: directory, project, project-local or user")
+	cmd.Flags().String("conflict", CONFLICT_REFUSE, "If the target level has a prompt of the same name: refuse, overwrite or merge")
+	cmd.MarkFlagRequired("to")
+}
+
+// validateConflict checks the value of the --conflict flag
+func validateConflict(conflict string) error {
+	switch conflict {
+	case CONFLICT_REFUSE, CONFLICT_OVERWRITE, CONFLICT_MERGE:
+		return nil
+	}
+	return fmt.Errorf("invalid --conflict %q (use %s, %s or %s)", conflict, CONFLICT_REFUSE, CONFLICT_OVERWRITE, CONFLICT_MERGE)
+}
+
+// transferPrompt copies the prompt name to the level target. A prompt of the
+// same name at the target level is handled according to conflict; its previous
+// content is recorded in the history under action
+func transferPrompt(manager prompt.Manager, history *prompt.History, name, target, conflict, action string) (*prompt.PromptInfo, *prompt.PromptInfo, error) {
+	if err := validateConflict(conflict); err != nil {
+		return nil, nil, err
+	}
+
+	source, err := manager.Get(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get prompt '%s': %w", name, err)
+	}
+	if source.Source == target {
+		return nil, nil, fmt.Errorf("prompt '%s' is already at the %s level", source.Name, target)
+	}
+	// At the project root the directory and project levels are the same directory
+	targetPath, err := manager.PathAt(source.Name, target, prompt.Extension(source.Path))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to write prompt '%s' to %s: %w", source.Name, target, err)
+	}
+	if samePath(targetPath, source.Path) {
+		return nil, nil, fmt.Errorf("prompt '%s' is already at the %s level (%s)", source.Name, target, source.Path)
+	}
+
+	copied, err := storePrompt(manager, history, source, target, conflict, action)
+	if err != nil {
+		return nil, nil, err
+	}
+	return source, copied, nil
+}
+
+// samePath reports whether a and b name the same file once made absolute
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return absA == absB
+}
+
+// storePrompt writes source as a prompt of the same name at the level target,
+// handling a prompt that already exists there according to conflict. A merge
+// is based on the newest content both prompts had according to history
+func storePrompt(manager prompt.Manager, history *prompt.History, source *prompt.PromptInfo, target, conflict, action string) (*prompt.PromptInfo, error) {
+	content := source.Content
+	existing, err := manager.GetAt(source.Name, target)
+	if err != nil && err != prompt.ErrPromptNotFound {
+		return nil, fmt.Errorf("failed to get prompt '%s:%s': %w", target, source.Name, err)
+	}
+	if existing != nil {
+		switch conflict {
+		case CONFLICT_REFUSE:
+			return nil, fmt.Errorf("prompt '%s' already exists at the %s level (%s); use --conflict overwrite or --conflict merge", source.Name, target, existing.Path)
+		case CONFLICT_MERGE:
+			base, err := mergeBase(history, existing, source)
+			if err != nil {
+				return nil, err
+			}
+			var conflicts int
+			content, conflicts = diff.Merge3(base, existing.Content, source.Content, target+":"+source.Name, source.Source+":"+source.Name)
+			if conflicts > 0 {
+				fmt.Printf("Merged with %d conflict(s); resolve the <<<<<<< markers in %s:%s\n", conflicts, target, source.Name)
+			}
+		}
+
+		if err := manager.Snapshot(existing, action); err != nil {
+			return nil, err
+		}
+
+		// Keep a single file per name, e.g. when review.txt is replaced by review.md
+		if prompt.Extension(existing.Path) != prompt.Extension(source.Path) {
+			if err := manager.Remove(existing); err != nil {
+				return nil, fmt.Errorf("failed to replace prompt '%s:%s': %w", target, source.Name, err)
+			}
+		}
+	}
+
+	stored, err := manager.Store(source.Name, content, target, prompt.Extension(source.Path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write prompt '%s' to %s: %w", source.Name, target, err)
+	}
+	return stored, nil
+}
+
+// mergeBase returns the newest content existing and source had in common. Without
+// one the base is empty, so all lines that differ end up in a conflict between both versions
+func mergeBase(history *prompt.History, existing, source *prompt.PromptInfo) (string, error) {
+	if history == nil {
+		return "", nil
+	}
+	base, _, err := history.Ancestor(existing, source)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the common version of '%s:%s' and '%s:%s': %w", existing.Source, existing.Name, source.Source, source.Name, err)
+	}
+	return base, nil
+}
+
+// printShadowing prints which level's prompt called name is now in use and which ones it shadows
+func printShadowing(manager prompt.Manager, name string) error {
+	prompts, err := manager.Catalog()
+	if err != nil {
+		return fmt.Errorf("failed to list prompts: %w", err)
+	}
+
+	var levels []string
+	for _, info := range prompts {
+		if info.Name == name {
+			levels = append(levels, info.Source+":"+info.Name)
+		}
+	}
+	if len(levels) > 1 {
+		fmt.Printf("%s now shadows %s\n", levels[0], strings.Join(levels[1:], ", "))
+	}
+	return nil
+}
@@ -1,20 +1,25 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"strings"
 
+	"github.com/dhamidi/proompt/pkg/diff"
 	"github.com/dhamidi/proompt/pkg/editor"
+	"github.com/dhamidi/proompt/pkg/filesystem"
 	"github.com/dhamidi/proompt/pkg/picker"
 	"github.com/dhamidi/proompt/pkg/prompt"
 	"github.com/spf13/cobra"
 )
 
 // editCmd creates the edit command
-func editCmd(manager prompt.Manager, pick picker.Picker, ed editor.Editor) *cobra.Command {
+func editCmd(manager prompt.Manager, pick picker.Picker, ed editor.Editor, fs filesystem.Filesystem) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "edit [name]",
 		Short: "Edit a prompt",
-		Long:  "Edit a prompt. If no name is provided, a picker will be used to select one. Use location flags to create new prompts at specific levels.",
+		Long:  "Edit a prompt. If no name is provided, a picker will be used to select one. Use location flags to create new prompts at specific levels. The editor works on a copy of the prompt; if the prompt file changes while the editor is open, you can merge both versions, overwrite the file or keep it unchanged.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get location flags
 			directory, _ := cmd.Flags().GetBool("directory")
@@ -54,21 +59,34 @@ func editCmd(manager prompt.Manager, pick picker.Picker, ed editor.Editor) *cobr
 			// If a name is provided as argument
 			if len(args) > 0 {
 				promptName = args[0]
-				
-				// Try to get existing prompt
-				promptInfo, err = manager.Get(promptName)
-				if err != nil && err != prompt.ErrPromptNotFound {
-					return fmt.Errorf("failed to get prompt: %w", err)
-				}
-
-				// If prompt doesn't exist and no location flag specified, error
-				if err == prompt.ErrPromptNotFound && locationCount == 0 {
-					return fmt.Errorf("prompt '%s' not found. Use a location flag (--directory, --project, --project-local, --user) to create it", promptName)
-				}
 
-				// If prompt exists and location flag specified, error
-				if err == nil && locationCount > 0 {
-					return fmt.Errorf("prompt '%s' already exists at %s. Cannot specify location flag for existing prompts", promptName, promptInfo.Source)
+				// A source-qualified name such as user:review edits or creates the prompt at that level,
+				// even if a prompt of the same name at another level shadows it
+				if source, bare := prompt.SplitQualifiedName(promptName); source != "" {
+					if locationCount > 0 && targetLocation != source {
+						return fmt.Errorf("prompt '%s' names the %s level, which conflicts with the --%s flag", promptName, source, targetLocation)
+					}
+					promptInfo, err = manager.Get(promptName)
+					if err != nil && err != prompt.ErrPromptNotFound {
+						return fmt.Errorf("failed to get prompt: %w", err)
+					}
+					promptName, targetLocation = bare, source
+				} else {
+					// Try to get existing prompt
+					promptInfo, err = manager.Get(promptName)
+					if err != nil && err != prompt.ErrPromptNotFound {
+						return fmt.Errorf("failed to get prompt: %w", err)
+					}
+
+					// If prompt doesn't exist and no location flag specified, error
+					if err == prompt.ErrPromptNotFound && locationCount == 0 {
+						return fmt.Errorf("prompt '%s' not found. Use a location flag (--directory, --project, --project-local, --user) to create it", promptName)
+					}
+
+					// If prompt exists and location flag specified, error
+					if err == nil && locationCount > 0 {
+						return fmt.Errorf("prompt '%s' already exists at %s. Cannot specify location flag for existing prompts", promptName, promptInfo.Source)
+					}
 				}
 			} else {
 				// No name provided, use picker to select existing prompt
@@ -98,7 +116,8 @@ func editCmd(manager prompt.Manager, pick picker.Picker, ed editor.Editor) *cobr
 			}
 
 			// Handle creating new prompt
-			if promptInfo == nil {
+			created := promptInfo == nil
+			if created {
 				// Create new prompt with empty content
 				err = manager.Create(promptName, "", targetLocation)
 				if err != nil {
@@ -106,19 +125,21 @@ func editCmd(manager prompt.Manager, pick picker.Picker, ed editor.Editor) *cobr
 				}
 
 				// Get the newly created prompt
-				promptInfo, err = manager.Get(promptName)
+				promptInfo, err = manager.GetAt(promptName, targetLocation)
 				if err != nil {
 					return fmt.Errorf("failed to get newly created prompt: %w", err)
 				}
 			}
 
-			// Edit the prompt file
-			err = ed.Edit(promptInfo.Path)
+			// Edit a working copy of the prompt file
+			saved, err := editPrompt(manager, ed, fs, promptInfo, !created, cmd.InOrStdin())
 			if err != nil {
-				return fmt.Errorf("editor failed: %w", err)
+				return err
 			}
 
-			fmt.Printf("Edited prompt: %s (%s)\n", promptInfo.Name, promptInfo.Source)
+			if saved {
+				fmt.Printf("Edited prompt: %s (%s)\n", promptInfo.Name, promptInfo.Source)
+			}
 			return nil
 		},
 	}
@@ -131,3 +152,101 @@ func editCmd(manager prompt.Manager, pick picker.Picker, ed editor.Editor) *cobr
 
 	return cmd
 }
+
+// How to save an edit when the prompt file changed while the editor was open
+const (
+	EDIT_MERGE     = "m" // three-way merge; conflicts are resolved in the editor
+	EDIT_OVERWRITE = "o" // replace the file with the edited copy
+	EDIT_KEEP      = "k" // leave the file alone and keep the edited copy
+)
+
+// editPrompt opens a working copy of info in the editor and saves it to the
+// prompt file. If the file changed in the meantime, the user decides how to
+// save through answers read from in. It returns whether the prompt was saved.
+// With snapshot set, the replaced content is recorded in the history.
+// Prompts from bundles are read-only and refused before the editor opens
+func editPrompt(manager prompt.Manager, ed editor.Editor, fs filesystem.Filesystem, info *prompt.PromptInfo, snapshot bool, in io.Reader) (bool, error) {
+	if info.Source == prompt.BUNDLE_SOURCE {
+		return false, fmt.Errorf("%w: prompt '%s' is part of a bundle; copy it to a writable level to change it, e.g. proompt cp %s:%s --to user", filesystem.ErrReadOnly, info.Name, info.Source, info.Name)
+	}
+
+	pattern := "proompt-" + strings.ReplaceAll(info.Name, "/", "-") + "-*" + prompt.Extension(info.Path)
+	workFile, err := fs.TempFile("", pattern)
+	if err != nil {
+		return false, fmt.Errorf("failed to create working copy: %w", err)
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			fs.Remove(workFile.Name())
+		}
+	}()
+	// keepEdit keeps the working copy so an edit that cannot be saved is not lost
+	keepEdit := func(err error) (bool, error) {
+		keep = true
+		fmt.Printf("Prompt left unchanged; your edit is in %s\n", workFile.Name())
+		return false, err
+	}
+
+	_, err = workFile.WriteString(info.Content)
+	workFile.Close()
+	if err != nil {
+		return false, fmt.Errorf("failed to write working copy: %w", err)
+	}
+
+	answers := bufio.NewReader(in)
+	base := *info // the content of the prompt file the working copy is based on
+	for {
+		if err := ed.Edit(workFile.Name()); err != nil {
+			return false, fmt.Errorf("editor failed: %w", err)
+		}
+		data, err := fs.ReadFile(workFile.Name())
+		if err != nil {
+			return false, fmt.Errorf("failed to read working copy: %w", err)
+		}
+		edited := string(data)
+
+		for {
+			if edited == base.Content {
+				return false, nil
+			}
+			if snapshot {
+				if err := manager.Snapshot(&base, "edit"); err != nil {
+					return keepEdit(err)
+				}
+			}
+
+			current, err := manager.Update(&base, edited)
+			if err == nil {
+				return true, nil
+			}
+			if err != prompt.ErrPromptChanged {
+				return keepEdit(fmt.Errorf("failed to save prompt: %w", err))
+			}
+
+			fmt.Printf("Prompt %s changed on disk while it was being edited.\n", info.Path)
+			fmt.Print("[m]erge, [o]verwrite or [k]eep the file on disk? [m] ")
+			answer, _ := answers.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case EDIT_OVERWRITE, "overwrite":
+				base.Content = current
+				continue
+			case EDIT_KEEP, "keep":
+				return keepEdit(nil)
+			}
+
+			merged, conflicts := diff.Merge3(base.Content, current, edited, "on disk", "your edit")
+			base.Content, edited = current, merged
+			if conflicts == 0 {
+				continue
+			}
+
+			// Let the user resolve the conflicts before saving again
+			fmt.Printf("Merged with %d conflict(s); resolve the <<<<<<< markers in the editor\n", conflicts)
+			if err := fs.WriteFile(workFile.Name(), []byte(merged), 0600); err != nil {
+				return false, fmt.Errorf("failed to write working copy: %w", err)
+			}
+			break
+		}
+	}
+}
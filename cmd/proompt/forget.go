@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dhamidi/proompt/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+// forgetCmd creates the forget command
+func forgetCmd(lastUsed *prompt.LastUsed) *cobra.Command {
+	return &cobra.Command{
+		Use:   "forget [name...]",
+		Short: "Clear the remembered values of prompts",
+		Long:  "Forget the placeholder values pick remembered for the named prompts, in all projects, so the next pick starts from the template defaults. Without names the values of all prompts are forgotten.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if lastUsed == nil {
+				return errors.New("last-used values are not remembered (PROOMPT_REMEMBER=off)")
+			}
+
+			cleared, err := lastUsed.Clear(args...)
+			if err != nil {
+				return fmt.Errorf("failed to forget values: %w", err)
+			}
+
+			if len(cleared) == 0 {
+				fmt.Println("No remembered values")
+				return nil
+			}
+			for _, name := range cleared {
+				fmt.Printf("Forgot values of %s\n", name)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dhamidi/proompt/pkg/diff"
+	"github.com/dhamidi/proompt/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+// errNoHistory is returned by the history commands when there is no user data directory
+var errNoHistory = errors.New("history is not available: no user data directory")
+
+// logCmd creates the log command
+func logCmd(history *prompt.History) *cobra.Command {
+	return &cobra.Command{
+		Use:   "log <name>",
+		Short: "List the revisions of a prompt",
+		Long:  "List the snapshots taken before edit, mv, cp, rename, revert or rm changed a prompt, newest first. Revisions of prompts with that name at all levels are listed unless the name is source-qualified, e.g. user:review.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if history == nil {
+				return errNoHistory
+			}
+
+			source, name := prompt.SplitQualifiedName(args[0])
+			revisions, err := history.Log(name)
+			if err != nil {
+				return err
+			}
+
+			count := 0
+			for _, revision := range revisions {
+				if source != "" && revision.Source != source {
+					continue
+				}
+				fmt.Printf("%s  %s  %-7s %s:%s\n", revision.ID(), revision.Time.Local().Format("2006-01-02 15:04:05"), revision.Action, revision.Source, name)
+				count++
+			}
+			if count == 0 {
+				fmt.Printf("No history for %s\n", args[0])
+			}
+			return nil
+		},
+	}
+}
+
+// diffCmd creates the diff command
+func diffCmd(manager prompt.Manager, history *prompt.History) *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff <name> [rev]",
+		Short: "Show the changes of a prompt since a revision",
+		Long:  "Show a unified diff from a revision of a prompt to its current content. Without a revision, the most recent snapshot is used.",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if history == nil {
+				return errNoHistory
+			}
+
+			current, revision, err := findRevision(manager, history, args)
+			if err != nil {
+				return err
+			}
+			old, err := history.Content(revision)
+			if err != nil {
+				return err
+			}
+
+			_, name := prompt.SplitQualifiedName(args[0])
+			newLabel, newContent := "(removed)", ""
+			if current != nil {
+				newLabel, newContent = current.Path, current.Content
+			}
+
+			unified := diff.Unified(name+"@"+revision.ID(), newLabel, old, newContent)
+			if unified == "" {
+				fmt.Printf("No changes since %s\n", revision.ID())
+				return nil
+			}
+			fmt.Print(unified)
+			return nil
+		},
+	}
+}
+
+// revertCmd creates the revert command
+func revertCmd(manager prompt.Manager, history *prompt.History) *cobra.Command {
+	return &cobra.Command{
+		Use:   "revert <name> <rev>",
+		Short: "Restore a prompt to a revision",
+		Long:  "Replace the content of a prompt with a revision from its history. The current content is snapshotted first, so a revert can be reverted. A removed prompt is recreated at the level it had in that revision.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if history == nil {
+				return errNoHistory
+			}
+
+			current, revision, err := findRevision(manager, history, args)
+			if err != nil {
+				return err
+			}
+			content, err := history.Content(revision)
+			if err != nil {
+				return err
+			}
+
+			_, name := prompt.SplitQualifiedName(args[0])
+			source, ext := revision.Source, prompt.Extension(revision.Path)
+			if current != nil {
+				if err := manager.Snapshot(current, "revert"); err != nil {
+					return err
+				}
+				source, ext = current.Source, prompt.Extension(current.Path)
+			} else {
+				// A removed prompt is only recreated where it was, not at the same level of another project
+				target, err := manager.PathAt(name, source, ext)
+				if err != nil {
+					return fmt.Errorf("failed to revert prompt: %w", err)
+				}
+				if !samePath(target, revision.Path) {
+					return fmt.Errorf("cannot revert removed prompt '%s': revision %s was taken of %s, not %s", args[0], revision.ID(), revision.Path, target)
+				}
+			}
+
+			reverted, err := manager.Store(name, content, source, ext)
+			if err != nil {
+				return fmt.Errorf("failed to revert prompt: %w", err)
+			}
+
+			fmt.Printf("Reverted prompt: %s (%s) to %s\n", reverted.Name, reverted.Source, revision.ID())
+			return nil
+		},
+	}
+}
+
+// findRevision returns the current prompt named by args[0], or nil if it does
+// not exist, and the revision args[1] of it; without args[1] the most recent
+// revision of the prompt's level
+func findRevision(manager prompt.Manager, history *prompt.History, args []string) (*prompt.PromptInfo, *prompt.Revision, error) {
+	source, name := prompt.SplitQualifiedName(args[0])
+
+	current, err := manager.Get(args[0])
+	if err != nil && err != prompt.ErrPromptNotFound {
+		return nil, nil, fmt.Errorf("failed to get prompt: %w", err)
+	}
+	if current != nil {
+		source = current.Source
+	}
+
+	if len(args) > 1 {
+		revision, err := history.Find(name, args[1])
+		if err != nil {
+			return nil, nil, err
+		}
+		return current, revision, nil
+	}
+
+	revisions, err := history.Log(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, revision := range revisions {
+		if source == "" || revision.Source == source {
+			return current, &revisions[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: no history for %s", prompt.ErrRevisionNotFound, args[0])
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/dhamidi/proompt/pkg/prompt"
 	"github.com/spf13/cobra"
@@ -9,16 +11,26 @@ import (
 
 // listCmd creates the list command
 func listCmd(manager prompt.Manager) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all available prompts",
-		Long:  "List all available prompts from all configured locations (directory, project, project-local, user)",
+		Long:  "List all available prompts from all configured locations (directory, project, project-local, user). Use --tree to group nested prompts such as git/commit by folder, and --all to include prompts overridden by a prompt of the same name at a higher level.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			prompts, err := manager.List()
+			tree, _ := cmd.Flags().GetBool("tree")
+			all, _ := cmd.Flags().GetBool("all")
+
+			catalog, err := manager.Catalog()
 			if err != nil {
 				return fmt.Errorf("failed to list prompts: %w", err)
 			}
 
+			var prompts []prompt.PromptInfo
+			for _, info := range catalog {
+				if all || info.ShadowedBy == "" {
+					prompts = append(prompts, info)
+				}
+			}
+
 			if len(prompts) == 0 {
 				fmt.Println("No prompts found")
 				return nil
@@ -26,11 +38,74 @@ func listCmd(manager prompt.Manager) *cobra.Command {
 
 			fmt.Printf("Found %d prompt(s):\n\n", len(prompts))
 
+			if tree {
+				printPromptTree(prompts)
+				return nil
+			}
+
 			for _, prompt := range prompts {
-				fmt.Printf("%-20s %-15s %s\n", prompt.Name, fmt.Sprintf("(%s)", prompt.Source), prompt.Path)
+				fmt.Printf("%-20s %-15s %s%s\n", prompt.Name, fmt.Sprintf("(%s)", prompt.Source), prompt.Path, shadowedNote(prompt))
+				if summary := metadataSummary(prompt.Metadata); summary != "" {
+					fmt.Printf("%-20s %s\n", "", summary)
+				}
 			}
 
 			return nil
 		},
 	}
+
+	cmd.Flags().Bool("tree", false, "Show prompts as a tree of folders")
+	cmd.Flags().Bool("all", false, "Include prompts overridden at a higher level")
+
+	return cmd
+}
+
+// printPromptTree prints prompts sorted by name, with each folder of a
+// nested name printed once above the prompts it contains
+func printPromptTree(prompts []prompt.PromptInfo) {
+	sorted := append([]prompt.PromptInfo(nil), prompts...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var folders []string
+	for _, info := range sorted {
+		segments := strings.Split(info.Name, "/")
+		dirs := segments[:len(segments)-1]
+
+		// Keep the folders shared with the previous prompt and open the new ones
+		common := 0
+		for common < len(folders) && common < len(dirs) && folders[common] == dirs[common] {
+			common++
+		}
+		for depth := common; depth < len(dirs); depth++ {
+			fmt.Printf("%s%s/\n", strings.Repeat("  ", depth), dirs[depth])
+		}
+		folders = dirs
+
+		indent := strings.Repeat("  ", len(dirs))
+		line := fmt.Sprintf("%s%-*s (%s)%s", indent, 20-len(indent), segments[len(segments)-1], info.Source, shadowedNote(info))
+		if summary := metadataSummary(info.Metadata); summary != "" {
+			line += " " + summary
+		}
+		fmt.Println(line)
+	}
+}
+
+// shadowedNote marks a prompt listed by --all that is overridden at a higher level
+func shadowedNote(info prompt.PromptInfo) string {
+	if info.ShadowedBy == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [overridden by %s:%s]", info.ShadowedBy, info.Name)
+}
+
+// metadataSummary formats the description and tags of a prompt for one line of output
+func metadataSummary(metadata prompt.Metadata) string {
+	parts := []string{}
+	if description := strings.Join(strings.Fields(metadata.Description), " "); description != "" {
+		parts = append(parts, description)
+	}
+	for _, tag := range metadata.Tags {
+		parts = append(parts, "#"+tag)
+	}
+	return strings.Join(parts, " ")
 }
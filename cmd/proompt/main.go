@@ -25,12 +25,53 @@ func main() {
 	}
 
 	// Initialize components
-	fs := filesystem.NewRealFilesystem(cwd)
+	fs := filesystem.NewMountFS(filesystem.NewRealFilesystem(cwd))
 	resolver := prompt.NewDefaultLocationResolver(fs)
+
+	// Mount bundles as read-only prompt locations
+	for _, bundle := range cfg.Bundles {
+		path, err := mountBundle(fs, bundle)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping bundle %s: %v\n", bundle, err)
+			continue
+		}
+		resolver.Bundles = append(resolver.Bundles, path)
+	}
+
 	manager := prompt.NewDefaultManager(fs, resolver)
+	index, err := prompt.NewIndex(fs)
+	if err == nil {
+		manager.Index = index
+	}
+	trash, err := prompt.NewTrash(fs)
+	if err == nil {
+		manager.Trash = trash
+	}
+	history, err := prompt.NewHistory(fs, resolver)
+	if err == nil {
+		manager.History = history
+	}
+	var lastUsed *prompt.LastUsed
+	if cfg.Remember != config.REMEMBER_OFF {
+		lastUsed, err = prompt.NewLastUsed(fs)
+		if err == nil && cfg.Remember == config.REMEMBER_PROJECT {
+			if root, err := prompt.FindProjectRoot(fs); err == nil {
+				lastUsed.Project = root
+			}
+		}
+	}
+	presets := prompt.NewPresets(fs, resolver)
 	pick := picker.NewRealPicker(cfg.Picker)
 	ed := editor.NewRealEditor(cfg.Editor)
 	parser := prompt.NewDefaultParser()
+
+	// Get paste command from environment or use default
+	pasteCommand := os.Getenv("PROOMPT_PASTE_COMMAND")
+	if pasteCommand == "" {
+		pasteCommand = "pbpaste"
+	}
+	parser.Sources = prompt.NewDefaultSourceRegistry(fs, copier.NewRealPaster(pasteCommand))
+	parser.Sources.Register("cmd", &prompt.CommandSource{Allowed: cfg.AllowCommands})
 	
 	// Get copy command from environment or use default
 	copyCommand := os.Getenv("PROOMPT_COPY_COMMAND")
@@ -49,10 +90,23 @@ func main() {
 	// Add subcommands
 	rootCmd.AddCommand(
 		listCmd(manager),
-		showCmd(manager),
-		editCmd(manager, pick, ed),
+		showCmd(manager, parser, fs),
+		editCmd(manager, pick, ed, fs),
 		rmCmd(manager, pick),
-		pickCmd(manager, pick, ed, parser, fs, cop),
+		mvCmd(manager, manager.History),
+		cpCmd(manager, manager.History),
+		renameCmd(manager),
+		restoreCmd(manager),
+		trashCmd(manager.Trash),
+		logCmd(manager.History),
+		diffCmd(manager, manager.History),
+		revertCmd(manager, manager.History),
+		packCmd(manager, fs),
+		unpackCmd(manager, manager.History),
+		pickCmd(manager, pick, ed, parser, fs, cop, lastUsed, presets),
+		forgetCmd(lastUsed),
+		presetCmd(presets, manager, parser, fs, lastUsed),
+		renderCmd(manager, parser, fs, presets),
 	)
 
 	if err := rootCmd.Execute(); err != nil {
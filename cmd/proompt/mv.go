@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dhamidi/proompt/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+// mvCmd creates the move command
+func mvCmd(manager prompt.Manager, history *prompt.History) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mv <name> --to <level>",
+		Short: "Move a prompt to another level",
+		Long:  "Move a prompt to another level of the hierarchy (directory, project, project-local, user), e.g. to promote a project-local draft to the shared project prompts. The name may be source-qualified, e.g. project-local:review.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target, _ := cmd.Flags().GetString("to")
+			conflict, _ := cmd.Flags().GetString("conflict")
+
+			source, moved, err := transferPrompt(manager, history, args[0], target, conflict, "mv")
+			if err != nil {
+				return err
+			}
+
+			if err := manager.Snapshot(source, "mv"); err != nil {
+				return err
+			}
+			// Presets the target level already has are kept; the source's go to the trash with it
+			if err := manager.MovePresets(source.Path, moved.Path); errors.Is(err, prompt.ErrPromptExists) {
+				fmt.Printf("Warning: %s:%s already has presets; the presets of %s:%s are removed with it\n", moved.Source, moved.Name, source.Source, source.Name)
+			} else if err != nil {
+				return fmt.Errorf("copied prompt to %s but failed to move its presets: %w", moved.Path, err)
+			}
+			if err := manager.Delete(source.Source + ":" + source.Name); err != nil {
+				return fmt.Errorf("copied prompt to %s but failed to remove %s: %w", moved.Path, source.Path, err)
+			}
+
+			fmt.Printf("Moved prompt: %s (%s -> %s)\n", source.Name, source.Source, moved.Source)
+			return printShadowing(manager, source.Name)
+		},
+	}
+
+	addTransferFlags(cmd)
+
+	return cmd
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/dhamidi/proompt/pkg/copier"
 	"github.com/dhamidi/proompt/pkg/editor"
@@ -21,18 +23,50 @@ func pickCmd(
 	parser prompt.Parser,
 	fs filesystem.Filesystem,
 	cop copier.Copier,
+	lastUsed *prompt.LastUsed,
+	presets *prompt.Presets,
 ) *cobra.Command {
-	return &cobra.Command{
-		Use:   "pick",
+	cmd := &cobra.Command{
+		Use:   "pick [name]",
 		Short: "Pick and process a prompt",
-		Long:  "Select a prompt, fill in placeholders, and output the final result.",
+		Long:  "Select a prompt, fill in placeholders, and output the final result. A prompt name, optionally qualified like user:review, skips the picker. Use --format json to output the messages of a chat prompt as a JSON array. The values a prompt was last picked with are pre-filled unless --no-last-used is given; proompt forget clears them. --preset pre-fills the values of a preset saved with proompt preset save instead.",
+		Args:  cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := runPickCommand(manager, pick, ed, parser, fs, cop); err != nil {
+			options := pickOptions{LastUsed: lastUsed, Presets: presets}
+			options.Format, _ = cmd.Flags().GetString("format")
+			options.IgnoreLastUsed, _ = cmd.Flags().GetBool("no-last-used")
+			options.Preset, _ = cmd.Flags().GetString("preset")
+			if len(args) > 0 {
+				options.Name = args[0]
+			}
+			if err := runPickCommand(manager, pick, ed, parser, fs, cop, options); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
 		},
 	}
+
+	cmd.Flags().String("format", prompt.FORMAT_MARKDOWN, "Output format: markdown or json")
+	cmd.Flags().Bool("no-last-used", false, "Start from the template defaults instead of the last used values")
+	cmd.Flags().String("preset", "", "Pre-fill the values of a preset, like security or project:security")
+
+	return cmd
+}
+
+// pickOptions holds the settings of a pick, mostly from the flags of the pick command
+type pickOptions struct {
+	Name           string           // prompt to use instead of asking the picker
+	Format         string           // one of the prompt.FORMAT_* formats; empty means markdown
+	LastUsed       *prompt.LastUsed // remembers the values of each pick if set
+	IgnoreLastUsed bool             // do not pre-fill the remembered values
+	Preset         string           // preset whose values are pre-filled, over the remembered ones
+	Presets        *prompt.Presets  // where Preset is looked up
+}
+
+// prefilledValue is a value offered for a placeholder instead of its default
+type prefilledValue struct {
+	Value any
+	Note  string // where the value comes from, e.g. "last used" or "preset security"
 }
 
 func runPickCommand(
@@ -42,46 +76,95 @@ func runPickCommand(
 	parser prompt.Parser,
 	fs filesystem.Filesystem,
 	cop copier.Copier,
+	options pickOptions,
 ) error {
-	// Step 1: Get all prompts using manager.GetAllForPicker()
-	items, err := manager.GetAllForPicker()
-	if err != nil {
-		return fmt.Errorf("failed to get prompts: %w", err)
+	if options.Format == "" {
+		options.Format = prompt.FORMAT_MARKDOWN
 	}
+	if options.Format != prompt.FORMAT_MARKDOWN && options.Format != prompt.FORMAT_JSON {
+		return fmt.Errorf("unknown output format %q (available: %s, %s)", options.Format, prompt.FORMAT_MARKDOWN, prompt.FORMAT_JSON)
+	}
+
+	// Step 1 and 2: Use the named prompt, or let the user pick one
+	name := options.Name
+	if name == "" {
+		items, err := manager.GetAllForPicker()
+		if err != nil {
+			return fmt.Errorf("failed to get prompts: %w", err)
+		}
 
-	if len(items) == 0 {
-		return fmt.Errorf("no prompts found")
+		if len(items) == 0 {
+			return fmt.Errorf("no prompts found")
+		}
+
+		selectedItem, err := pick.Pick(items)
+		if err != nil {
+			return fmt.Errorf("failed to pick prompt: %w", err)
+		}
+		name = selectedItem.Name
 	}
 
-	// Step 2: Use picker.Pick() to let user select
-	selectedItem, err := pick.Pick(items)
+	// Step 3: Load the prompt and collect its variables
+	loaded, err := loadPrompt(manager, parser, name)
 	if err != nil {
-		return fmt.Errorf("failed to pick prompt: %w", err)
+		return err
 	}
+	promptInfo, frontmatter, body := loaded.Info, loaded.Frontmatter, loaded.Body
+	warnDiagnostics(loaded)
+	includes, engine, messages := loaded.Includes, loaded.Engine, loaded.Messages
 
-	// Get the full prompt content
-	promptInfo, err := manager.Get(selectedItem.Name)
-	if err != nil {
-		return fmt.Errorf("failed to get prompt content: %w", err)
+	var preset *prompt.Preset
+	if options.Preset != "" {
+		if options.Presets == nil {
+			return errors.New("presets are not available")
+		}
+		if preset, err = options.Presets.Get(promptInfo.Name, options.Preset); err != nil {
+			return err
+		}
 	}
 
-	// Step 3: Parse selected prompt with parser.ParsePlaceholders()
-	placeholders, err := parser.ParsePlaceholders(promptInfo.Content)
+	// Compute defaults that come from value sources (@file, @cmd, @env, @clipboard)
+	placeholders, err := loaded.Parser.ResolveDefaults(loaded.Placeholders)
 	if err != nil {
-		return fmt.Errorf("failed to parse placeholders: %w", err)
+		return fmt.Errorf("failed to resolve placeholder defaults: %w", err)
 	}
 
+	// Placeholders without an inline default take the one from the defaults: block
+	placeholders = frontmatter.ApplyDefaults(placeholders)
+
 	// If no placeholders, just output the content directly
 	if len(placeholders) == 0 {
-		fmt.Print(promptInfo.Content)
-		if err := cop.Copy(promptInfo.Content); err != nil {
+		output, err := formatOutput(promptInfo, messages, options.Format)
+		if err != nil {
+			return err
+		}
+		fmt.Print(output)
+		if err := cop.Copy(output); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to copy to clipboard: %v\n", err)
 		}
 		return nil
 	}
 
+	// Offer the values the prompt was last used with, or those of the preset,
+	// instead of the defaults
+	prefilled := make(map[string]prefilledValue)
+	if options.LastUsed != nil && !options.IgnoreLastUsed {
+		remembered, err := options.LastUsed.Get(promptInfo.Name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		for name, value := range remembered {
+			prefilled[name] = prefilledValue{Value: value, Note: "last used"}
+		}
+	}
+	if preset != nil {
+		for name, value := range preset.Values {
+			prefilled[name] = prefilledValue{Value: value, Note: "preset " + preset.Name}
+		}
+	}
+
 	// Step 4: Create temporary file with placeholders and defaults
-	tempContent := generateMarkdownPlaceholderFile(placeholders, promptInfo.Content)
+	tempContent := generateMarkdownPlaceholderFile(placeholders, frontmatter.Variables, prefilled, body)
 
 	tempFile, err := fs.TempFile("", "proompt-*.md")
 	if err != nil {
@@ -108,26 +191,240 @@ func runPickCommand(
 		return fmt.Errorf("failed to read edited file: %w", err)
 	}
 
-	values, templateContent, err := parseMarkdownEditedValues(string(editedContent))
+	// Check if file was saved empty (abort signal) before validating anything
+	if len(strings.TrimSpace(string(editedContent))) == 0 {
+		return fmt.Errorf("operation aborted (empty file)")
+	}
+
+	values, templateContent, err := parseMarkdownEditedValues(string(editedContent), frontmatter.Variables)
 	if err != nil {
 		return fmt.Errorf("failed to parse edited content: %w", err)
 	}
 
-	// Check if file was saved empty (abort signal)
-	if len(strings.TrimSpace(string(editedContent))) == 0 {
-		return fmt.Errorf("operation aborted (empty file)")
+	// Reuse computed defaults for placeholders removed from the frontmatter
+	// instead of running their value sources a second time
+	for _, p := range placeholders {
+		if _, exists := values[p.Name]; !exists && p.Source != "" {
+			values[p.Name] = p.DefaultValue
+		}
+		// Unchanged defaults that reference other placeholders are evaluated
+		// with the values entered for those placeholders
+		if value, ok := values[p.Name].(string); ok && len(p.DependsOn) > 0 && value == p.DefaultValue {
+			delete(values, p.Name)
+		}
+	}
+
+	// Built-in variables such as ${git.branch} are reserved and always filled in
+	for name, value := range prompt.BuiltinValues(fs, promptInfo, time.Now()) {
+		values[name] = value
 	}
 
 	// Step 7: Output final prompt to stdout (use edited template content)
-	finalContent := parser.SubstitutePlaceholders(templateContent, values)
+	messages, err = expandMessages(includes, promptInfo, templateContent)
+	if err != nil {
+		return err
+	}
+	messages, err = renderMessages(engine, messages, values)
+	if err != nil {
+		return fmt.Errorf("failed to substitute placeholders: %w", err)
+	}
+	finalContent, err := formatOutput(promptInfo, messages, options.Format)
+	if err != nil {
+		return err
+	}
 	fmt.Print(finalContent)
 	if err := cop.Copy(finalContent); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to copy to clipboard: %v\n", err)
 	}
 
+	if options.LastUsed != nil {
+		if err := options.LastUsed.Save(promptInfo.Name, rememberedValues(placeholders, values)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remember values: %v\n", err)
+		}
+	}
+
 	return nil
 }
 
+// rememberedValues returns the values worth pre-filling next time: those
+// entered for placeholders that differ from the default. Computed defaults,
+// such as @cmd output, are computed again each time and never remembered
+func rememberedValues(placeholders []prompt.Placeholder, values map[string]any) map[string]any {
+	remembered := make(map[string]any)
+	for _, p := range placeholders {
+		value, ok := values[p.Name]
+		if !ok || p.Source != "" || prompt.FormatValue(value) == p.DefaultValue {
+			continue
+		}
+		remembered[p.Name] = value
+	}
+	return remembered
+}
+
+// loadedPrompt is a prompt ready to be rendered
+type loadedPrompt struct {
+	Info         *prompt.PromptInfo
+	Frontmatter  *prompt.Frontmatter // merged with the prompt it extends
+	Body         string              // the body without frontmatter, before includes are expanded
+	Messages     []prompt.Message    // the messages of the body with includes expanded
+	Includes     *prompt.IncludeResolver
+	Engine       prompt.Engine
+	Parser       prompt.Parser        // resolves defaults; refuses @cmd if any content came from a bundle
+	Placeholders []prompt.Placeholder // as declared; computed and frontmatter defaults are not applied yet
+	Diagnostics  []prompt.Diagnostic  // malformed ${...} of the native engine, kept as text
+}
+
+// loadPrompt gets the prompt name, merges it into the prompt it extends,
+// expands its includes and collects its variables
+func loadPrompt(manager prompt.Manager, parser prompt.Parser, name string) (*loadedPrompt, error) {
+	// Get the full prompt content
+	recorder := newSourceRecorder(manager)
+	promptInfo, err := recorder.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt content: %w", err)
+	}
+
+	// Separate the prompt's own frontmatter (variable schema) from its body,
+	// merged into the prompt it extends, if any
+	frontmatter, body, err := prompt.NewExtendsResolver(recorder).Parse(promptInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt '%s': %w", promptInfo.Name, err)
+	}
+
+	// Expand include directives so placeholders of included prompts are offered too
+	includes := prompt.NewIncludeResolver(recorder)
+	messages, err := expandMessages(includes, promptInfo, body)
+	if err != nil {
+		return nil, err
+	}
+	parser = recorder.parserFor(parser)
+
+	// The frontmatter selects the template engine; native ${} placeholders by default
+	engine, err := prompt.NewDefaultEngineRegistry(parser).Lookup(frontmatter.Engine)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt '%s': %w", promptInfo.Name, err)
+	}
+
+	placeholders, err := engine.Variables(joinMessages(messages))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse placeholders: %w", err)
+	}
+
+	var diagnostics []prompt.Diagnostic
+	if frontmatter.Engine == "" || frontmatter.Engine == prompt.ENGINE_NATIVE {
+		diagnostics = prompt.ParseTemplate(joinMessages(messages)).Diagnostics
+	}
+
+	return &loadedPrompt{
+		Info:         promptInfo,
+		Frontmatter:  frontmatter,
+		Body:         body,
+		Messages:     messages,
+		Includes:     includes,
+		Engine:       engine,
+		Parser:       parser,
+		Placeholders: placeholders,
+		Diagnostics:  diagnostics,
+	}, nil
+}
+
+// warnDiagnostics tells on stderr that a prompt has malformed placeholders,
+// which are output as they are; show lists them
+func warnDiagnostics(loaded *loadedPrompt) {
+	if len(loaded.Diagnostics) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d malformed placeholder(s) in '%s' are kept as text; run 'proompt show %s' to list them\n", len(loaded.Diagnostics), loaded.Info.Name, loaded.Info.Name)
+	}
+}
+
+// sourceRecorder is a Manager that records the levels of the prompts it
+// returns, so content pulled in through extends: and includes can be traced
+type sourceRecorder struct {
+	prompt.Manager
+	sources map[string]bool
+}
+
+// newSourceRecorder creates a sourceRecorder on top of manager
+func newSourceRecorder(manager prompt.Manager) *sourceRecorder {
+	return &sourceRecorder{
+		Manager: manager,
+		sources: make(map[string]bool),
+	}
+}
+
+// Get implements prompt.Manager
+func (r *sourceRecorder) Get(name string) (*prompt.PromptInfo, error) {
+	info, err := r.Manager.Get(name)
+	if err == nil {
+		r.sources[info.Source] = true
+	}
+	return info, err
+}
+
+// GetBelow implements prompt.Manager
+func (r *sourceRecorder) GetBelow(name, source string) (*prompt.PromptInfo, error) {
+	info, err := r.Manager.GetBelow(name, source)
+	if err == nil {
+		r.sources[info.Source] = true
+	}
+	return info, err
+}
+
+// parserFor returns the parser for the prompts recorded so far. If any of
+// them came from a bundle, @cmd refuses to run: commands never run for
+// third-party prompts, however the reference is written
+func (r *sourceRecorder) parserFor(parser prompt.Parser) prompt.Parser {
+	if defaultParser, ok := parser.(*prompt.DefaultParser); ok && r.sources[prompt.BUNDLE_SOURCE] {
+		return defaultParser.ForSource(prompt.BUNDLE_SOURCE)
+	}
+	return parser
+}
+
+// expandMessages splits a prompt body into its messages and expands the includes of each
+func expandMessages(includes *prompt.IncludeResolver, info *prompt.PromptInfo, body string) ([]prompt.Message, error) {
+	messages, err := prompt.SplitMessages(info.Path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt '%s': %w", info.Name, err)
+	}
+	for i := range messages {
+		messages[i].Content, err = includes.Expand(info.Name, messages[i].Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand includes: %w", err)
+		}
+	}
+	return messages, nil
+}
+
+// joinMessages joins the content of all messages so their variables can be collected at once
+func joinMessages(messages []prompt.Message) string {
+	contents := make([]string, len(messages))
+	for i, message := range messages {
+		contents[i] = message.Content
+	}
+	return strings.Join(contents, "\n")
+}
+
+// renderMessages renders the content of every message with the same values
+func renderMessages(engine prompt.Engine, messages []prompt.Message, values map[string]any) ([]prompt.Message, error) {
+	rendered := make([]prompt.Message, len(messages))
+	for i, message := range messages {
+		content, err := engine.Render(message.Content, values)
+		if err != nil {
+			return nil, err
+		}
+		rendered[i] = prompt.Message{Role: message.Role, Content: content}
+	}
+	return rendered, nil
+}
+
+// formatOutput writes the messages of a prompt in format. Markdown prompts
+// are a single message and are printed as is in markdown format
+func formatOutput(info *prompt.PromptInfo, messages []prompt.Message, format string) (string, error) {
+	if format == prompt.FORMAT_MARKDOWN && !prompt.IsChatPrompt(info.Path) {
+		return messages[0].Content, nil
+	}
+	return prompt.FormatMessages(messages, format)
+}
+
 // generatePlaceholderFile creates the placeholder editing experience
 func generatePlaceholderFile(placeholders []prompt.Placeholder, originalContent string) string {
 	var buf strings.Builder
@@ -170,24 +467,55 @@ func parseEditedValues(content string) (map[string]string, error) {
 }
 
 // generateMarkdownPlaceholderFile creates the markdown frontmatter editing experience
-func generateMarkdownPlaceholderFile(placeholders []prompt.Placeholder, originalContent string) string {
+// Variables declared in schema are annotated with their description and type.
+// Prefilled values replace the defaults of their placeholders
+func generateMarkdownPlaceholderFile(placeholders []prompt.Placeholder, schema prompt.Schema, prefilled map[string]prefilledValue, originalContent string) string {
 	var buf strings.Builder
 	
 	// Write YAML frontmatter
 	buf.WriteString("---\n")
 	
 	if len(placeholders) > 0 {
-		// Create map for YAML marshaling
-		vars := make(map[string]string)
-		for _, p := range placeholders {
-			vars[p.Name] = p.DefaultValue
+		// Build the mapping node by hand so required variables can be flagged with a comment.
+		// Variables come before the variables whose defaults reference them
+		sorted := prompt.SortPlaceholders(placeholders)
+
+		vars := &yaml.Node{Kind: yaml.MappingNode}
+		for _, p := range sorted {
+			spec := schema[p.Name]
+			key := &yaml.Node{Kind: yaml.ScalarNode, Value: p.Name, HeadComment: spec.Hint()}
+			value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: p.DefaultValue}
+			// Offer structured values in flow style, e.g. FILES: []; list defaults hold one item per line
+			if spec.Type == prompt.TYPE_LIST {
+				value = &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+				for _, item := range strings.Split(p.DefaultValue, "\n") {
+					if strings.TrimSpace(item) != "" {
+						value.Content = append(value.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+					}
+				}
+			} else if p.DefaultValue == "" && spec.Type == prompt.TYPE_MAP {
+				value = &yaml.Node{Kind: yaml.MappingNode, Style: yaml.FlowStyle}
+			}
+			var notes []string
+			if p.Required || spec.Required {
+				notes = append(notes, "required")
+			}
+			if p.Source != "" {
+				notes = append(notes, "from "+p.Source)
+			}
+			if pre, ok := prefilled[p.Name]; ok {
+				value = prefilledNode(pre.Value)
+				notes = append(notes, pre.Note)
+			}
+			value.LineComment = strings.Join(notes, ", ")
+			vars.Content = append(vars.Content, key, value)
 		}
 		
 		// Marshal to YAML
 		yamlBytes, err := yaml.Marshal(vars)
 		if err != nil {
 			// Fallback to simple format if YAML marshaling fails
-			for _, p := range placeholders {
+			for _, p := range sorted {
 				buf.WriteString(fmt.Sprintf("%s: %s\n", p.Name, p.DefaultValue))
 			}
 		} else {
@@ -202,18 +530,20 @@ func generateMarkdownPlaceholderFile(placeholders []prompt.Placeholder, original
 }
 
 // parseMarkdownEditedValues parses edited values from markdown with frontmatter
-func parseMarkdownEditedValues(content string) (map[string]string, string, error) {
+// and validates them against schema, reporting every violation.
+// Values keep their YAML types, so lists and mappings stay structured
+func parseMarkdownEditedValues(content string, schema prompt.Schema) (map[string]any, string, error) {
 	content = strings.TrimSpace(content)
 	
 	// Handle empty content
 	if content == "" {
-		return make(map[string]string), "", nil
+		return make(map[string]any), "", nil
 	}
 	
 	// Check if content starts with frontmatter delimiter
 	if !strings.HasPrefix(content, "---\n") {
 		// No frontmatter, treat entire content as markdown
-		return make(map[string]string), content, nil
+		return make(map[string]any), content, nil
 	}
 	
 	// Find the end of frontmatter
@@ -235,19 +565,40 @@ func parseMarkdownEditedValues(content string) (map[string]string, string, error
 	contentLines := lines[frontmatterEnd+1:]
 	
 	// Parse YAML frontmatter
-	vars := make(map[string]string)
+	vars := make(map[string]any)
 	if len(frontmatterLines) > 0 {
 		frontmatterYAML := strings.Join(frontmatterLines, "\n")
 		if strings.TrimSpace(frontmatterYAML) != "" {
-			err := yaml.Unmarshal([]byte(frontmatterYAML), &vars)
+			decoded, err := prompt.DecodeValues([]byte(frontmatterYAML))
 			if err != nil {
 				return nil, "", fmt.Errorf("invalid YAML frontmatter: %w", err)
 			}
+			vars = decoded
 		}
 	}
 	
+	if err := schema.Validate(vars); err != nil {
+		return nil, "", err
+	}
+	
 	// Join content lines
 	markdownContent := strings.Join(contentLines, "\n")
 	
 	return vars, markdownContent, nil
 }
+
+// prefilledNode returns the YAML node offering value; lists and mappings are
+// written in flow style like the empty values offered for their types
+func prefilledNode(value any) *yaml.Node {
+	if text, ok := value.(string); ok {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: text}
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: prompt.FormatValue(value)}
+	}
+	if node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode {
+		node.Style = yaml.FlowStyle
+	}
+	return node
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -76,11 +77,34 @@ func TestGenerateMarkdownPlaceholderFile(t *testing.T) {
 					strings.Contains(result, "Message: ${MESSAGE}\nList: ${LIST}")
 			},
 		},
+		{
+			name: "required placeholders are flagged",
+			placeholders: []prompt.Placeholder{
+				{Name: "CODE", Required: true, Operators: []string{prompt.OP_REQUIRED}},
+				{Name: "LANGUAGE", DefaultValue: "Go", HasDefault: true},
+			},
+			content: "Review ${CODE:?paste code} in ${LANGUAGE:-Go}.",
+			checkFunc: func(result string) bool {
+				return strings.Contains(result, `CODE: "" # required`) &&
+					strings.Contains(result, "LANGUAGE: Go\n")
+			},
+		},
+		{
+			name: "dependencies come before dependent defaults",
+			placeholders: []prompt.Placeholder{
+				{Name: "ATITLE", DefaultValue: "Fix for ${ZISSUE}", HasDefault: true, DependsOn: []string{"ZISSUE"}},
+				{Name: "ZISSUE"},
+			},
+			content: "${ATITLE:-Fix for ${ZISSUE}}",
+			checkFunc: func(result string) bool {
+				return strings.HasPrefix(result, "---\nZISSUE: \"\"\nATITLE: Fix for ${ZISSUE}\n---\n")
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := generateMarkdownPlaceholderFile(tt.placeholders, tt.content)
+			result := generateMarkdownPlaceholderFile(tt.placeholders, nil, nil, tt.content)
 			if !tt.checkFunc(result) {
 				t.Errorf("generateMarkdownPlaceholderFile() failed validation. Got: %q", result)
 			}
@@ -92,7 +116,7 @@ func TestParseMarkdownEditedValues(t *testing.T) {
 	tests := []struct {
 		name        string
 		content     string
-		expectedVars map[string]string
+		expectedVars map[string]any
 		expectedContent string
 		expectError bool
 	}{
@@ -103,7 +127,7 @@ NAME: Alice
 SCORE: 95
 ---
 Hello ${NAME}! Your score is ${SCORE}.`,
-			expectedVars: map[string]string{
+			expectedVars: map[string]any{
 				"NAME":  "Alice",
 				"SCORE": "95",
 			},
@@ -115,14 +139,14 @@ Hello ${NAME}! Your score is ${SCORE}.`,
 			content: `---
 ---
 Simple content without variables.`,
-			expectedVars: map[string]string{},
+			expectedVars: map[string]any{},
 			expectedContent: "Simple content without variables.",
 			expectError: false,
 		},
 		{
 			name: "no frontmatter",
 			content: "Just plain content",
-			expectedVars: map[string]string{},
+			expectedVars: map[string]any{},
 			expectedContent: "Just plain content",
 			expectError: false,
 		},
@@ -135,7 +159,7 @@ TITLE: My Document
 
 This is a multiline
 document with content.`,
-			expectedVars: map[string]string{
+			expectedVars: map[string]any{
 				"TITLE": "My Document",
 			},
 			expectedContent: "# ${TITLE}\n\nThis is a multiline\ndocument with content.",
@@ -149,7 +173,7 @@ PATH: "/path/to/file"
 ---
 Message: ${MESSAGE}
 Path: ${PATH}`,
-			expectedVars: map[string]string{
+			expectedVars: map[string]any{
 				"MESSAGE": "Hello: world!",
 				"PATH": "/path/to/file",
 			},
@@ -168,7 +192,7 @@ TEXT: |-
 ---
 Config: ${CONFIG}
 Text: ${TEXT}`,
-			expectedVars: map[string]string{
+			expectedVars: map[string]any{
 				"CONFIG": "key1: value1\nkey2: value2\n",
 				"TEXT": "Line 1\nLine 2",
 			},
@@ -188,7 +212,7 @@ Content here`,
 		{
 			name: "empty file",
 			content: "",
-			expectedVars: map[string]string{},
+			expectedVars: map[string]any{},
 			expectedContent: "",
 			expectError: false,
 		},
@@ -205,7 +229,7 @@ VAR: value
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			vars, content, err := parseMarkdownEditedValues(tt.content)
+			vars, content, err := parseMarkdownEditedValues(tt.content, nil)
 			
 			if tt.expectError {
 				if err == nil {
@@ -238,6 +262,22 @@ VAR: value
 	}
 }
 
+func TestParseMarkdownEditedValuesStructured(t *testing.T) {
+	vars, _, err := parseMarkdownEditedValues("---\nFILES: [a.go, b.go]\nOWNER:\n  name: ada\nVERSION: 1.10\n---\nBody", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]any{
+		"FILES":   []any{"a.go", "b.go"},
+		"OWNER":   map[string]any{"name": "ada"},
+		"VERSION": "1.10",
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("parseMarkdownEditedValues() = %#v, want %#v", vars, expected)
+	}
+}
+
 func TestParseMarkdownEditedValuesBackwardCompatibility(t *testing.T) {
 	// Test that the function can still handle the old format for compatibility
 	// if needed (VAR=value format)
@@ -247,7 +287,7 @@ SCORE=95
 # Full prompt preview:
 # Hello ${NAME}! Your score is ${SCORE}.`
 
-	vars, _, err := parseMarkdownEditedValues(content)
+	vars, _, err := parseMarkdownEditedValues(content, nil)
 	if err != nil {
 		t.Errorf("Parsing old format should not error: %v", err)
 	}
@@ -258,3 +298,79 @@ SCORE=95
 		t.Error("Old format should not parse variables in new function")
 	}
 }
+
+func TestGenerateMarkdownPlaceholderFileWithSchema(t *testing.T) {
+	placeholders := []prompt.Placeholder{
+		{Name: "LANGUAGE", DefaultValue: "Go", HasDefault: true},
+		{Name: "FOCUS"},
+	}
+	schema := prompt.Schema{
+		"LANGUAGE": {Type: prompt.TYPE_ENUM, Description: "Programming language", Values: []string{"Go", "Python"}, Required: true},
+	}
+
+	result := generateMarkdownPlaceholderFile(placeholders, schema, nil, "Review ${LANGUAGE} for ${FOCUS}.")
+
+	expected := "---\nFOCUS: \"\"\n# Programming language; one of: Go, Python\nLANGUAGE: Go # required\n---\n"
+	if !strings.HasPrefix(result, expected) {
+		t.Errorf("generateMarkdownPlaceholderFile() = %q, want prefix %q", result, expected)
+	}
+}
+
+func TestGenerateMarkdownPlaceholderFileStructuredTypes(t *testing.T) {
+	placeholders := []prompt.Placeholder{{Name: "FILES"}, {Name: "OWNER"}}
+	schema := prompt.Schema{
+		"FILES": {Type: prompt.TYPE_LIST},
+		"OWNER": {Type: prompt.TYPE_MAP},
+	}
+
+	result := generateMarkdownPlaceholderFile(placeholders, schema, nil, "${FILES}")
+
+	expected := "---\n# list\nFILES: []\n# map\nOWNER: {}\n---\n"
+	if !strings.HasPrefix(result, expected) {
+		t.Errorf("generateMarkdownPlaceholderFile() = %q, want prefix %q", result, expected)
+	}
+}
+
+func TestParseMarkdownEditedValuesValidatesSchema(t *testing.T) {
+	schema := prompt.Schema{
+		"LANGUAGE": {Type: prompt.TYPE_ENUM, Values: []string{"Go", "Python"}},
+		"COUNT":    {Type: prompt.TYPE_INT, Required: true},
+	}
+
+	_, _, err := parseMarkdownEditedValues("---\nLANGUAGE: Golang\nCOUNT: \"\"\n---\nBody", schema)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, want := range []string{"LANGUAGE", "COUNT"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to report %s, got: %v", want, err)
+		}
+	}
+
+	vars, _, err := parseMarkdownEditedValues("---\nLANGUAGE: Go\nCOUNT: 3\n---\nBody", schema)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if vars["COUNT"] != "3" {
+		t.Errorf("Expected COUNT to be 3, got %q", vars["COUNT"])
+	}
+}
+
+func TestGenerateMarkdownPlaceholderFilePrefilled(t *testing.T) {
+	placeholders := []prompt.Placeholder{
+		{Name: "FILES"},
+		{Name: "LANGUAGE", DefaultValue: "Go", HasDefault: true},
+	}
+	schema := prompt.Schema{"FILES": {Type: prompt.TYPE_LIST}}
+	prefilled := map[string]prefilledValue{
+		"FILES":    {Value: []any{"a.go", "b.go"}, Note: "last used"},
+		"LANGUAGE": {Value: "Rust", Note: "last used"},
+	}
+
+	result := generateMarkdownPlaceholderFile(placeholders, schema, prefilled, "${FILES}")
+
+	expected := "---\n# list\nFILES: [a.go, b.go] # last used\nLANGUAGE: Rust # last used\n---\n"
+	if !strings.HasPrefix(result, expected) {
+		t.Errorf("generateMarkdownPlaceholderFile() = %q, want prefix %q", result, expected)
+	}
+}
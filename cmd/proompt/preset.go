@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+	"github.com/dhamidi/proompt/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+// presetCmd creates the preset command with its save, list and delete subcommands
+func presetCmd(presets *prompt.Presets, manager prompt.Manager, parser prompt.Parser, fs filesystem.Filesystem, lastUsed *prompt.LastUsed) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "preset",
+		Short: "Manage named sets of placeholder values",
+		Long:  "Presets are named sets of placeholder values for a prompt, applied with 'proompt pick --preset <name>' or 'proompt render --preset <name>'. They are kept in <prompt>.presets.yaml at any level of the hierarchy, so a team can share presets in the project prompts/ directory. A preset overrides presets of the same name at lower levels.",
+	}
+
+	save := &cobra.Command{
+		Use:   "save <prompt> <preset>",
+		Short: "Save placeholder values as a preset",
+		Long:  "Save a preset of the prompt from --values files and --set KEY=VALUE assignments, which work like those of render. Without either, the values the prompt was last picked with are saved. An existing preset of the same name at the level is replaced.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target, _ := cmd.Flags().GetString("to")
+			sets, _ := cmd.Flags().GetStringArray("set")
+			valueFiles, _ := cmd.Flags().GetStringArray("values")
+
+			loaded, err := loadPrompt(manager, parser, args[0])
+			if err != nil {
+				return err
+			}
+
+			var values map[string]any
+			if len(sets) == 0 && len(valueFiles) == 0 {
+				if lastUsed == nil {
+					return fmt.Errorf("no values given for preset %s; use --set or --values", args[1])
+				}
+				if values, err = lastUsed.Get(loaded.Info.Name); err != nil {
+					return err
+				}
+				if len(values) == 0 {
+					return fmt.Errorf("no values given for preset %s and %s has no last used values; use --set or --values", args[1], loaded.Info.Name)
+				}
+			} else {
+				values, err = readValues(fs, cmd.InOrStdin(), valueFiles, sets)
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := checkPresetValues(loaded, values); err != nil {
+				return err
+			}
+
+			preset, err := presets.Save(loaded.Info.Name, args[1], target, values)
+			if err != nil {
+				return fmt.Errorf("failed to save preset: %w", err)
+			}
+			fmt.Printf("Saved preset %s of %s at the %s level: %s\n", preset.Name, preset.Prompt, preset.Source, presetSummary(preset.Values))
+			return nil
+		},
+	}
+	save.Flags().String("to", "user", "Level to save the preset at: directory, project, project-local or user")
+	save.Flags().StringArray("set", nil, "Set a placeholder: KEY=VALUE, KEY=@file or KEY=@- for stdin (repeatable)")
+	save.Flags().StringArray("values", nil, "Read placeholder values from a YAML or JSON file, - for stdin (repeatable)")
+
+	list := &cobra.Command{
+		Use:   "list [prompt]",
+		Short: "List the presets of a prompt, or of all prompts",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			promptName := ""
+			if len(args) > 0 {
+				promptName = args[0]
+			}
+
+			found, err := presets.List(promptName)
+			if err != nil {
+				return fmt.Errorf("failed to list presets: %w", err)
+			}
+			if len(found) == 0 {
+				fmt.Println("No presets found")
+				return nil
+			}
+
+			for _, preset := range found {
+				note := ""
+				if preset.ShadowedBy != "" {
+					note = fmt.Sprintf(" [overridden by %s:%s]", preset.ShadowedBy, preset.Name)
+				}
+				fmt.Printf("%-20s %-15s %-15s %s%s\n", preset.Prompt, preset.Name, fmt.Sprintf("(%s)", preset.Source), presetSummary(preset.Values), note)
+			}
+			return nil
+		},
+	}
+
+	remove := &cobra.Command{
+		Use:     "delete <prompt> <preset>",
+		Aliases: []string{"rm"},
+		Short:   "Delete a preset",
+		Long:    "Delete the preset from the highest level that has it. Qualify the preset name with a level, like project:security, to delete it from that level instead.",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			preset, err := presets.Delete(args[0], args[1])
+			if err != nil {
+				return fmt.Errorf("failed to delete preset: %w", err)
+			}
+			fmt.Printf("Deleted preset %s of %s from the %s level\n", preset.Name, preset.Prompt, preset.Source)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(save, list, remove)
+	return cmd
+}
+
+// checkPresetValues rejects values for names that are not placeholders of the
+// loaded prompt, which are most likely typos
+func checkPresetValues(loaded *loadedPrompt, values map[string]any) error {
+	known := make(map[string]bool, len(loaded.Placeholders))
+	for _, p := range loaded.Placeholders {
+		known[p.Name] = true
+	}
+
+	var unknown []string
+	for name := range values {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("'%s' has no placeholders named %s", loaded.Info.Name, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// presetSummary formats the values of a preset for one line of output
+func presetSummary(values map[string]any) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		value := strings.ReplaceAll(prompt.FormatValue(values[name]), "\n", ", ")
+		parts = append(parts, name+"="+value)
+	}
+	return strings.Join(parts, " ")
+}
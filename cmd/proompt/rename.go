@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/dhamidi/proompt/pkg/diff"
+	"github.com/dhamidi/proompt/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+// renameCmd creates the rename command
+func renameCmd(manager prompt.Manager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rename <old> <new>",
+		Short: "Rename a prompt and update references to it",
+		Long:  "Rename a prompt within its level and rewrite the includes and extends: fields of other prompts that refer to it. The rewrites are shown as a diff and applied after confirmation. The old name may be source-qualified, e.g. user:review.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			yes, _ := cmd.Flags().GetBool("yes")
+
+			plan, err := manager.PlanRename(args[0], args[1])
+			if err != nil {
+				if err == prompt.ErrPromptNotFound {
+					return fmt.Errorf("prompt '%s' not found", args[0])
+				}
+				return fmt.Errorf("failed to rename prompt: %w", err)
+			}
+
+			fmt.Printf("Rename %s:%s -> %s:%s\n", plan.Prompt.Source, plan.Prompt.Name, plan.Prompt.Source, plan.NewName)
+			fmt.Printf("  %s -> %s\n", plan.Prompt.Path, plan.NewPath)
+			for _, rewrite := range plan.Rewrites {
+				fmt.Print(diff.Unified(rewrite.Prompt.Path, rewrite.Prompt.Path, rewrite.Prompt.Content, rewrite.Content))
+			}
+			for _, info := range plan.ReadOnly {
+				fmt.Printf("Warning: %s:%s refers to %s but cannot be updated: bundles are read-only\n", info.Source, info.Name, plan.Prompt.Name)
+			}
+
+			if !yes && len(plan.Rewrites) > 0 {
+				fmt.Printf("Rewrite references in %d prompt(s)? [y/N] ", len(plan.Rewrites))
+				answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				answer = strings.ToLower(strings.TrimSpace(answer))
+				if answer != "y" && answer != "yes" {
+					fmt.Println("Aborted, nothing was changed")
+					return nil
+				}
+			}
+
+			if err := manager.Snapshot(&plan.Prompt, "rename"); err != nil {
+				return err
+			}
+			for _, rewrite := range plan.Rewrites {
+				if err := manager.Snapshot(&rewrite.Prompt, "rename"); err != nil {
+					return err
+				}
+			}
+
+			if err := manager.Rename(plan); err != nil {
+				return fmt.Errorf("failed to rename prompt: %w", err)
+			}
+
+			fmt.Printf("Renamed prompt: %s -> %s (%s), updated %d reference(s)\n", plan.Prompt.Name, plan.NewName, plan.Prompt.Source, len(plan.Rewrites))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolP("yes", "y", false, "Apply the rewrites without asking")
+
+	return cmd
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+	"github.com/dhamidi/proompt/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+// renderCmd creates the render command
+func renderCmd(manager prompt.Manager, parser prompt.Parser, fs filesystem.Filesystem, presets *prompt.Presets) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "render <name>",
+		Short: "Render a prompt without the picker or editor",
+		Long: `Render a prompt to stdout with values from the command line, for use in scripts, Makefiles and CI.
+
+Values are read from a --preset, then from --values files (YAML or JSON mappings, - for stdin) and then
+from --set KEY=VALUE, each taking precedence over the ones before. --set KEY=@path reads the value from a file, --set KEY=@- from stdin; write @@ for
+a value that starts with a literal @. Placeholders without a value use their defaults. With --strict,
+placeholders that have neither a value nor a default, and malformed placeholders such as ${}, are reported
+as an error instead of rendering empty or as text.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options := renderOptions{Presets: presets}
+			options.Name = args[0]
+			options.Sets, _ = cmd.Flags().GetStringArray("set")
+			options.ValueFiles, _ = cmd.Flags().GetStringArray("values")
+			options.Strict, _ = cmd.Flags().GetBool("strict")
+			options.Format, _ = cmd.Flags().GetString("format")
+			options.Preset, _ = cmd.Flags().GetString("preset")
+
+			output, err := runRenderCommand(manager, parser, fs, cmd.InOrStdin(), options)
+			if err != nil {
+				return err
+			}
+			fmt.Print(output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArray("set", nil, "Set a placeholder: KEY=VALUE, KEY=@file or KEY=@- for stdin (repeatable)")
+	cmd.Flags().StringArray("values", nil, "Read placeholder values from a YAML or JSON file, - for stdin (repeatable)")
+	cmd.Flags().Bool("strict", false, "Fail if a placeholder has neither a value nor a default, or is malformed")
+	cmd.Flags().String("format", prompt.FORMAT_MARKDOWN, "Output format: markdown or json")
+	cmd.Flags().String("preset", "", "Start from the values of a preset; --values and --set override them")
+
+	return cmd
+}
+
+// renderOptions holds the flags of the render command
+type renderOptions struct {
+	Name       string
+	Sets       []string // KEY=VALUE assignments
+	ValueFiles []string // YAML or JSON files of values
+	Strict     bool
+	Format     string          // one of the prompt.FORMAT_* formats; empty means markdown
+	Preset     string          // preset whose values come before the files and sets
+	Presets    *prompt.Presets // where Preset is looked up
+}
+
+// runRenderCommand renders the prompt options.Name with the values given in
+// options and returns the output. stdin is read for @- values and - files
+func runRenderCommand(manager prompt.Manager, parser prompt.Parser, fs filesystem.Filesystem, stdin io.Reader, options renderOptions) (string, error) {
+	if options.Format == "" {
+		options.Format = prompt.FORMAT_MARKDOWN
+	}
+	if options.Format != prompt.FORMAT_MARKDOWN && options.Format != prompt.FORMAT_JSON {
+		return "", fmt.Errorf("unknown output format %q (available: %s, %s)", options.Format, prompt.FORMAT_MARKDOWN, prompt.FORMAT_JSON)
+	}
+
+	values, err := readValues(fs, stdin, options.ValueFiles, options.Sets)
+	if err != nil {
+		return "", err
+	}
+
+	loaded, err := loadPrompt(manager, parser, options.Name)
+	if err != nil {
+		return "", err
+	}
+	if options.Strict && len(loaded.Diagnostics) > 0 {
+		return "", fmt.Errorf("'%s': %w", loaded.Info.Name, &prompt.DiagnosticsError{Diagnostics: loaded.Diagnostics})
+	}
+	warnDiagnostics(loaded)
+
+	if options.Preset != "" {
+		if options.Presets == nil {
+			return "", errors.New("presets are not available")
+		}
+		preset, err := options.Presets.Get(loaded.Info.Name, options.Preset)
+		if err != nil {
+			return "", err
+		}
+		for name, value := range preset.Values {
+			if _, ok := values[name]; !ok {
+				values[name] = value
+			}
+		}
+	}
+
+	// Only compute the defaults of placeholders without a value, so value
+	// sources such as @cmd do not run needlessly
+	var pending []prompt.Placeholder
+	for _, p := range loaded.Placeholders {
+		if _, ok := values[p.Name]; !ok {
+			pending = append(pending, p)
+		}
+	}
+	pending, err = loaded.Parser.ResolveDefaults(pending)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve placeholder defaults: %w", err)
+	}
+	pending = loaded.Frontmatter.ApplyDefaults(pending)
+
+	if options.Strict {
+		if missing := prompt.UnresolvedPlaceholders(pending, values); len(missing) > 0 {
+			return "", fmt.Errorf("%w in '%s' (set them with --set NAME=value):\n  - %s", prompt.ErrUnresolvedPlaceholder, loaded.Info.Name, strings.Join(missing, "\n  - "))
+		}
+	}
+
+	// Fill in defaults like pick does; defaults that reference other
+	// placeholders are evaluated while rendering
+	for _, p := range pending {
+		if !p.HasDefault || len(p.DependsOn) > 0 {
+			continue
+		}
+		values[p.Name] = p.DefaultValue
+		if value, ok := loaded.Frontmatter.Defaults[p.Name]; ok && p.DefaultValue == prompt.FormatValue(value) {
+			values[p.Name] = value // keep structured defaults structured
+		}
+	}
+
+	if err := loaded.Frontmatter.Variables.Validate(values); err != nil {
+		return "", err
+	}
+
+	// Built-in variables such as ${git.branch} are reserved and always filled in
+	for name, value := range prompt.BuiltinValues(fs, loaded.Info, time.Now()) {
+		values[name] = value
+	}
+
+	messages, err := renderMessages(loaded.Engine, loaded.Messages, values)
+	if err != nil {
+		return "", fmt.Errorf("failed to substitute placeholders: %w", err)
+	}
+	return formatOutput(loaded.Info, messages, options.Format)
+}
+
+// readValues reads the values of the files valueFiles and then of the
+// KEY=VALUE assignments sets, so that sets win. stdin is read at most once
+func readValues(fs filesystem.Filesystem, stdin io.Reader, valueFiles, sets []string) (map[string]any, error) {
+	input := &stdinOnce{reader: stdin}
+	values := make(map[string]any)
+	for _, path := range valueFiles {
+		fileValues, err := readValuesFile(fs, input, path)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range fileValues {
+			values[name] = value
+		}
+	}
+	for _, set := range sets {
+		name, value, err := parseSetValue(fs, input, set)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// parseSetValue parses a --set KEY=VALUE assignment. A VALUE of @path is read
+// from the file path, @- from stdin and @@text stands for the literal @text
+func parseSetValue(fs filesystem.Filesystem, stdin *stdinOnce, set string) (string, string, error) {
+	name, value, found := strings.Cut(set, "=")
+	if !found || strings.TrimSpace(name) == "" {
+		return "", "", fmt.Errorf("invalid --set %q (use KEY=VALUE)", set)
+	}
+	name = strings.TrimSpace(name)
+
+	switch {
+	case strings.HasPrefix(value, "@@"):
+		return name, value[1:], nil
+	case value == "@-":
+		data, err := stdin.Read()
+		if err != nil {
+			return "", "", fmt.Errorf("--set %s: %w", name, err)
+		}
+		return name, string(data), nil
+	case strings.HasPrefix(value, "@"):
+		data, err := fs.ReadFile(value[1:])
+		if err != nil {
+			return "", "", fmt.Errorf("--set %s: %w", name, err)
+		}
+		return name, string(data), nil
+	}
+	return name, value, nil
+}
+
+// readValuesFile reads a YAML or JSON mapping of values from path, or from stdin if path is -
+func readValuesFile(fs filesystem.Filesystem, stdin *stdinOnce, path string) (map[string]any, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = stdin.Read()
+	} else {
+		data, err = fs.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values: %w", err)
+	}
+
+	values, err := prompt.DecodeValues(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid values in %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// stdinOnce reads stdin for the one value or file that asks for it
+type stdinOnce struct {
+	reader io.Reader
+	read   bool
+}
+
+// Read returns all of stdin; it fails if stdin was already read
+func (s *stdinOnce) Read() ([]byte, error) {
+	if s.read {
+		return nil, errors.New("stdin can only be read once")
+	}
+	s.read = true
+	return io.ReadAll(s.reader)
+}
@@ -13,7 +13,7 @@ func rmCmd(manager prompt.Manager, pick picker.Picker) *cobra.Command {
 	return &cobra.Command{
 		Use:   "rm [name]",
 		Short: "Remove a prompt",
-		Long:  "Remove a prompt. If no name is provided, a picker will be used to select one.",
+		Long:  "Remove a prompt. If no name is provided, a picker will be used to select one. Removed prompts are moved to the trash and can be brought back with 'proompt restore <name>'.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var promptName string
 			var err error
@@ -49,6 +49,10 @@ func rmCmd(manager prompt.Manager, pick picker.Picker) *cobra.Command {
 				return fmt.Errorf("failed to get prompt: %w", err)
 			}
 
+			if err := manager.Snapshot(promptInfo, "rm"); err != nil {
+				return err
+			}
+
 			// Delete the prompt
 			err = manager.Delete(promptName)
 			if err != nil {
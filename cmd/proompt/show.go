@@ -2,22 +2,29 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/dhamidi/proompt/pkg/filesystem"
 	"github.com/dhamidi/proompt/pkg/prompt"
 	"github.com/spf13/cobra"
 )
 
 // showCmd creates the show command
-func showCmd(manager prompt.Manager) *cobra.Command {
-	return &cobra.Command{
+func showCmd(manager prompt.Manager, parser prompt.Parser, fs filesystem.Filesystem) *cobra.Command {
+	cmd := &cobra.Command{
 		Use:   "show <name>",
 		Short: "Show a specific prompt",
-		Long:  "Show the content of a specific prompt by name",
+		Long:  "Show the content of a specific prompt by name. Use --rendered to expand includes and default values.",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name := args[0]
+			rendered, _ := cmd.Flags().GetBool("rendered")
 			
-			promptInfo, err := manager.Get(name)
+			recorder := newSourceRecorder(manager)
+			promptInfo, err := recorder.Get(name)
 			if err != nil {
 				return fmt.Errorf("failed to get prompt '%s': %w", name, err)
 			}
@@ -26,12 +33,101 @@ func showCmd(manager prompt.Manager) *cobra.Command {
 				return fmt.Errorf("prompt '%s' not found", name)
 			}
 
+			own, ownBody, err := prompt.ParsePrompt(promptInfo)
+			if err != nil {
+				return fmt.Errorf("failed to parse prompt '%s': %w", name, err)
+			}
+
+			// The rest works on the prompt merged into the prompt it extends
+			frontmatter, body, err := prompt.NewExtendsResolver(recorder).Parse(promptInfo)
+			if err != nil {
+				return fmt.Errorf("failed to parse prompt '%s': %w", name, err)
+			}
+
+			// Report malformed placeholders with positions relative to the file
+			if frontmatter.Engine == "" || frontmatter.Engine == prompt.ENGINE_NATIVE {
+				lineOffset := strings.Count(promptInfo.Content, "\n") - strings.Count(ownBody, "\n")
+				for _, d := range prompt.ParseTemplate(ownBody).Diagnostics {
+					fmt.Fprintf(os.Stderr, "Warning: %s:%d:%d: %s\n", promptInfo.Path, d.Span.Start.Line+lineOffset, d.Span.Start.Column, d.Message)
+				}
+			}
+
+			engine, err := prompt.NewDefaultEngineRegistry(parser).Lookup(frontmatter.Engine)
+			if err != nil {
+				return fmt.Errorf("failed to parse prompt '%s': %w", name, err)
+			}
+
+			content := body
+			if rendered {
+				messages, err := expandMessages(prompt.NewIncludeResolver(recorder), promptInfo, body)
+				if err != nil {
+					return err
+				}
+				// Includes may pull in bundle prompts, whose @cmd defaults must not run
+				engine, err = prompt.NewDefaultEngineRegistry(recorder.parserFor(parser)).Lookup(frontmatter.Engine)
+				if err != nil {
+					return fmt.Errorf("failed to parse prompt '%s': %w", name, err)
+				}
+				values := make(map[string]any)
+				for name, value := range frontmatter.Defaults {
+					values[name] = value
+				}
+				for name, value := range prompt.BuiltinValues(fs, promptInfo, time.Now()) {
+					values[name] = value
+				}
+				messages, err = renderMessages(engine, messages, values)
+				if err != nil {
+					return fmt.Errorf("failed to render prompt '%s': %w", name, err)
+				}
+				content, err = formatOutput(promptInfo, messages, prompt.FORMAT_MARKDOWN)
+				if err != nil {
+					return err
+				}
+			}
+
 			fmt.Printf("Name: %s\n", promptInfo.Name)
 			fmt.Printf("Source: %s\n", promptInfo.Source)
 			fmt.Printf("Path: %s\n", promptInfo.Path)
-			fmt.Printf("\nContent:\n%s\n", promptInfo.Content)
+			if own.Extends != "" {
+				fmt.Printf("Extends: %s\n", own.Extends)
+			}
+			printMetadata(own.Metadata)
+			fmt.Printf("\nContent:\n%s\n", content)
 
 			return nil
 		},
 	}
+
+	cmd.Flags().Bool("rendered", false, "Expand includes and substitute default and built-in values")
+
+	return cmd
+}
+
+// printMetadata prints the descriptive frontmatter fields that are set
+func printMetadata(metadata prompt.Metadata) {
+	fields := []struct{ label, value string }{
+		{"Description", metadata.Description},
+		{"Tags", strings.Join(metadata.Tags, ", ")},
+		{"Author", metadata.Author},
+		{"Version", metadata.Version},
+		{"Model", metadata.Model},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			fmt.Printf("%s: %s\n", field.label, field.value)
+		}
+	}
+
+	if len(metadata.Defaults) > 0 {
+		fmt.Println("Defaults:")
+		names := make([]string, 0, len(metadata.Defaults))
+		for name := range metadata.Defaults {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := strings.ReplaceAll(prompt.FormatValue(metadata.Defaults[name]), "\n", "\n    ")
+			fmt.Printf("  %s: %s\n", name, value)
+		}
+	}
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+// trashCmd creates the trash command with its list and purge subcommands
+func trashCmd(trash *prompt.Trash) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trash",
+		Short: "Inspect and empty the trash of removed prompts",
+		Long:  "Removed prompts are kept in $XDG_DATA_HOME/proompt/trash together with the level and path they were removed from. Use 'proompt restore <name>' to put one back.",
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List removed prompts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if trash == nil {
+				return fmt.Errorf("trash is not available: no user data directory")
+			}
+
+			entries, err := trash.List()
+			if err != nil {
+				return fmt.Errorf("failed to list trash: %w", err)
+			}
+			if len(entries) == 0 {
+				fmt.Println("Trash is empty")
+				return nil
+			}
+
+			for _, entry := range entries {
+				fmt.Printf("%s  %s:%s  %s\n", entry.DeletedAt.Local().Format("2006-01-02 15:04"), entry.Source, entry.Name, entry.Path)
+			}
+			return nil
+		},
+	}
+
+	purge := &cobra.Command{
+		Use:   "purge",
+		Short: "Permanently delete removed prompts",
+		Long:  "Permanently delete removed prompts. With --older-than, only prompts removed longer ago than the given age (e.g. 30d, 12h) are deleted.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if trash == nil {
+				return fmt.Errorf("trash is not available: no user data directory")
+			}
+
+			olderThan, _ := cmd.Flags().GetString("older-than")
+			age, err := parseAge(olderThan)
+			if err != nil {
+				return err
+			}
+
+			purged, err := trash.Purge(age)
+			for _, entry := range purged {
+				fmt.Printf("Purged: %s:%s (%s)\n", entry.Source, entry.Name, entry.Path)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Purged %d prompt(s)\n", len(purged))
+			return nil
+		},
+	}
+	purge.Flags().String("older-than", "", "Only purge prompts removed longer ago than this age, e.g. 30d or 12h")
+
+	cmd.AddCommand(list, purge)
+	return cmd
+}
+
+// restoreCmd creates the restore command
+func restoreCmd(manager prompt.Manager) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <name>",
+		Short: "Restore a removed prompt",
+		Long:  "Restore the most recently removed prompt with the given name to its original level and path. The name may be source-qualified, e.g. project-local:review.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info, err := manager.Restore(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to restore prompt: %w", err)
+			}
+
+			fmt.Printf("Restored prompt: %s (%s) to %s\n", info.Name, info.Source, info.Path)
+			return nil
+		},
+	}
+}
+
+// parseAge parses an age such as 30d, 2w or any time.ParseDuration value; empty means zero
+func parseAge(text string) (time.Duration, error) {
+	if text == "" {
+		return 0, nil
+	}
+
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if number, ok := strings.CutSuffix(text, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n < 0 {
+				break
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	age, err := time.ParseDuration(text)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w or 12h)", text)
+	}
+	return age, nil
+}
@@ -1,18 +1,35 @@
 package config
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Where pick remembers the values a prompt was last used with
+const (
+	REMEMBER_PROMPT  = "prompt"  // one set of values per prompt
+	REMEMBER_PROJECT = "project" // one set of values per prompt and project
+	REMEMBER_OFF     = "off"     // values are not remembered
+)
 
 // Config holds application configuration
 type Config struct {
-	Editor string
-	Picker string
+	Editor        string
+	Picker        string
+	Bundles       []string // bundle archives mounted as read-only prompt locations
+	Remember      string   // one of the REMEMBER_* scopes
+	AllowCommands bool     // whether @cmd defaults may run shell commands
 }
 
 // Load loads configuration from environment variables
 func Load() *Config {
 	return &Config{
-		Editor: getEnv("EDITOR", "nano"),
-		Picker: getEnv("PROOMPT_PICKER", "fzf"),
+		Editor:        getEnv("EDITOR", "nano"),
+		Picker:        getEnv("PROOMPT_PICKER", "fzf"),
+		Bundles:       filepath.SplitList(os.Getenv("PROOMPT_BUNDLES")),
+		Remember:      getEnv("PROOMPT_REMEMBER", REMEMBER_PROMPT),
+		AllowCommands: getBool("PROOMPT_ALLOW_CMD"),
 	}
 }
 
@@ -23,3 +40,9 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getBool reports whether the environment variable key is set to a true value such as 1 or true
+func getBool(key string) bool {
+	value, _ := strconv.ParseBool(os.Getenv(key))
+	return value
+}
@@ -70,6 +70,50 @@ func TestLoad(t *testing.T) {
 	})
 }
 
+func TestLoadBundles(t *testing.T) {
+	t.Setenv("PROOMPT_BUNDLES", "")
+	if bundles := Load().Bundles; len(bundles) != 0 {
+		t.Errorf("Load() Bundles = %q, want none", bundles)
+	}
+
+	t.Setenv("PROOMPT_BUNDLES", "/bundles/team.zip"+string(os.PathListSeparator)+"shared.tar.gz")
+	bundles := Load().Bundles
+	if len(bundles) != 2 || bundles[0] != "/bundles/team.zip" || bundles[1] != "shared.tar.gz" {
+		t.Errorf("Load() Bundles = %q, want both bundles", bundles)
+	}
+}
+
+func TestLoadRemember(t *testing.T) {
+	t.Setenv("PROOMPT_REMEMBER", "")
+	if remember := Load().Remember; remember != REMEMBER_PROMPT {
+		t.Errorf("Load() Remember = %q, want %q", remember, REMEMBER_PROMPT)
+	}
+
+	t.Setenv("PROOMPT_REMEMBER", REMEMBER_PROJECT)
+	if remember := Load().Remember; remember != REMEMBER_PROJECT {
+		t.Errorf("Load() Remember = %q, want %q", remember, REMEMBER_PROJECT)
+	}
+}
+
+func TestLoadAllowCommands(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "", want: false},
+		{value: "0", want: false},
+		{value: "yes please", want: false},
+		{value: "1", want: true},
+		{value: "true", want: true},
+	}
+	for _, tt := range tests {
+		t.Setenv("PROOMPT_ALLOW_CMD", tt.value)
+		if allowed := Load().AllowCommands; allowed != tt.want {
+			t.Errorf("Load() AllowCommands with PROOMPT_ALLOW_CMD=%q = %v, want %v", tt.value, allowed, tt.want)
+		}
+	}
+}
+
 func TestGetEnv(t *testing.T) {
 	// Save original environment variable
 	original := os.Getenv("TEST_VAR")
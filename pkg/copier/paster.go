@@ -0,0 +1,60 @@
+package copier
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Paster interface abstracts reading content from the clipboard
+type Paster interface {
+	Paste() (string, error)
+}
+
+// RealPaster uses an external paste command
+type RealPaster struct {
+	Command string
+}
+
+// NewRealPaster creates a new RealPaster with the given command
+func NewRealPaster(command string) *RealPaster {
+	return &RealPaster{
+		Command: command,
+	}
+}
+
+// Paste executes the paste command and returns its output
+func (p *RealPaster) Paste() (string, error) {
+	if p.Command == "" {
+		return "", fmt.Errorf("no paste command configured")
+	}
+
+	cmd := exec.Command("sh", "-c", p.Command)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("paste command failed: %w", err)
+	}
+
+	return string(output), nil
+}
+
+// FakePaster simulates clipboard reads for testing
+type FakePaster struct {
+	Content    string
+	ShouldFail bool
+	PasteCount int
+}
+
+// NewFakePaster creates a new FakePaster
+func NewFakePaster() *FakePaster {
+	return &FakePaster{}
+}
+
+// Paste returns the predefined clipboard content
+func (p *FakePaster) Paste() (string, error) {
+	if p.ShouldFail {
+		return "", fmt.Errorf("paste failed")
+	}
+
+	p.PasteCount++
+	return p.Content, nil
+}
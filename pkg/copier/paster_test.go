@@ -0,0 +1,53 @@
+package copier
+
+import (
+	"testing"
+)
+
+func TestFakePaster(t *testing.T) {
+	paster := NewFakePaster()
+	paster.Content = "clipboard content"
+
+	content, err := paster.Paste()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content != "clipboard content" {
+		t.Errorf("expected %q, got %q", "clipboard content", content)
+	}
+	if paster.PasteCount != 1 {
+		t.Errorf("expected paste count 1, got %d", paster.PasteCount)
+	}
+
+	paster.ShouldFail = true
+	if _, err := paster.Paste(); err == nil {
+		t.Error("expected error but got none")
+	}
+}
+
+func TestRealPaster(t *testing.T) {
+	t.Run("echo command", func(t *testing.T) {
+		paster := NewRealPaster("printf 'from clipboard'")
+		content, err := paster.Paste()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if content != "from clipboard" {
+			t.Errorf("expected %q, got %q", "from clipboard", content)
+		}
+	})
+
+	t.Run("empty command", func(t *testing.T) {
+		paster := NewRealPaster("")
+		if _, err := paster.Paste(); err == nil {
+			t.Error("expected error for empty command")
+		}
+	})
+
+	t.Run("failing command", func(t *testing.T) {
+		paster := NewRealPaster("exit 1")
+		if _, err := paster.Paste(); err == nil {
+			t.Error("expected error for failing command")
+		}
+	})
+}
@@ -0,0 +1,237 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CONTEXT_LINES is the number of unchanged lines shown around each change of a unified diff
+const CONTEXT_LINES = 3
+
+// Kind identifies an edit operation
+type Kind int
+
+const (
+	Equal Kind = iota
+	Delete
+	Insert
+)
+
+// Edit is one line of a line-based diff
+type Edit struct {
+	Kind Kind
+	Line string // including its trailing newline, if any
+}
+
+// Lines splits text into lines that keep their trailing newline
+func Lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Compute returns the edits that turn a into b, based on a longest common subsequence of lines
+func Compute(a, b []string) []Edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, Edit{Kind: Equal, Line: a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, Edit{Kind: Delete, Line: a[i]})
+			i++
+		default:
+			edits = append(edits, Edit{Kind: Insert, Line: b[j]})
+			j++
+		}
+	}
+	return edits
+}
+
+// Unified returns a unified diff from a to b labelled with aName and bName,
+// or "" if the texts are equal
+func Unified(aName, bName, a, b string) string {
+	edits := Compute(Lines(a), Lines(b))
+
+	var buf strings.Builder
+	for start := 0; start < len(edits); {
+		// Find the next change and extend the hunk while changes are close together
+		first := start
+		for first < len(edits) && edits[first].Kind == Equal {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		last := first
+		for k := first; k < len(edits); k++ {
+			if edits[k].Kind != Equal {
+				last = k
+			} else if k-last > 2*CONTEXT_LINES {
+				break
+			}
+		}
+		from := max(first-CONTEXT_LINES, start)
+		to := min(last+CONTEXT_LINES+1, len(edits))
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(&buf, "--- %s\n+++ %s\n", aName, bName)
+		}
+		writeHunk(&buf, edits, from, to)
+		start = to
+	}
+	return buf.String()
+}
+
+// writeHunk writes edits[from:to] as one @@ hunk
+func writeHunk(buf *strings.Builder, edits []Edit, from, to int) {
+	aStart, bStart := 1, 1
+	for _, edit := range edits[:from] {
+		if edit.Kind != Insert {
+			aStart++
+		}
+		if edit.Kind != Delete {
+			bStart++
+		}
+	}
+	aCount, bCount := 0, 0
+	for _, edit := range edits[from:to] {
+		if edit.Kind != Insert {
+			aCount++
+		}
+		if edit.Kind != Delete {
+			bCount++
+		}
+	}
+	if aCount == 0 {
+		aStart--
+	}
+	if bCount == 0 {
+		bStart--
+	}
+
+	fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	for _, edit := range edits[from:to] {
+		prefix := " "
+		switch edit.Kind {
+		case Delete:
+			prefix = "-"
+		case Insert:
+			prefix = "+"
+		}
+		buf.WriteString(prefix + edit.Line)
+		if !strings.HasSuffix(edit.Line, "\n") {
+			buf.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// Merge3 merges the changes ours and theirs made to base. Where both changed the
+// same lines differently, the result contains a conflict between <<<<<<< ourLabel,
+// ======= and >>>>>>> theirLabel markers. It returns the merged text and the number of conflicts
+func Merge3(base, ours, theirs, ourLabel, theirLabel string) (string, int) {
+	baseLines, ourLines, theirLines := Lines(base), Lines(ours), Lines(theirs)
+	ourMatch := matches(baseLines, ourLines)
+	theirMatch := matches(baseLines, theirLines)
+
+	var buf strings.Builder
+	conflicts := 0
+	// resolve writes the chunk between stable lines; b, o and t are the chunk ends
+	i, o, t := 0, 0, 0
+	resolve := func(b, oe, te int) {
+		baseChunk, ourChunk, theirChunk := baseLines[i:b], ourLines[o:oe], theirLines[t:te]
+		switch {
+		case equalLines(ourChunk, baseChunk):
+			buf.WriteString(strings.Join(theirChunk, ""))
+		case equalLines(theirChunk, baseChunk), equalLines(ourChunk, theirChunk):
+			buf.WriteString(strings.Join(ourChunk, ""))
+		default:
+			conflicts++
+			buf.WriteString("<<<<<<< " + ourLabel + "\n")
+			writeLines(&buf, ourChunk)
+			buf.WriteString("=======\n")
+			writeLines(&buf, theirChunk)
+			buf.WriteString(">>>>>>> " + theirLabel + "\n")
+		}
+	}
+
+	for b := range baseLines {
+		oe, inOurs := ourMatch[b]
+		te, inTheirs := theirMatch[b]
+		if !inOurs || !inTheirs || oe < o || te < t {
+			continue
+		}
+		// base[b] is unchanged on both sides and separates two chunks
+		resolve(b, oe, te)
+		buf.WriteString(baseLines[b])
+		i, o, t = b+1, oe+1, te+1
+	}
+	resolve(len(baseLines), len(ourLines), len(theirLines))
+
+	return buf.String(), conflicts
+}
+
+// matches maps the index of each line of a kept unchanged in b to its index in b
+func matches(a, b []string) map[int]int {
+	matched := make(map[int]int)
+	i, j := 0, 0
+	for _, edit := range Compute(a, b) {
+		switch edit.Kind {
+		case Equal:
+			matched[i] = j
+			i++
+			j++
+		case Delete:
+			i++
+		case Insert:
+			j++
+		}
+	}
+	return matched
+}
+
+// equalLines reports whether a and b hold the same lines
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeLines writes the lines of a conflict side, ending the last one with a newline so a marker can follow
+func writeLines(buf *strings.Builder, lines []string) {
+	for _, line := range lines {
+		buf.WriteString(line)
+	}
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		buf.WriteString("\n")
+	}
+}
@@ -0,0 +1,86 @@
+package diff
+
+import (
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			a:    "one\ntwo\nthree\n",
+			b:    "one\n2\nthree\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+		{
+			name: "new file",
+			a:    "",
+			b:    "added",
+			want: "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+added\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a", "b", tt.a, tt.b); got != tt.want {
+				t.Errorf("Unified() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	base := "title\nintro\nchecklist\nfooter\n"
+
+	tests := []struct {
+		name          string
+		ours, theirs  string
+		want          string
+		wantConflicts int
+	}{
+		{
+			name:   "changes to different lines",
+			ours:   "Title\nintro\nchecklist\nfooter\n",
+			theirs: "title\nintro\nchecklist\nfooter\nthanks\n",
+			want:   "Title\nintro\nchecklist\nfooter\nthanks\n",
+		},
+		{
+			name:   "same change on both sides",
+			ours:   "title\nintro\nGo checklist\nfooter\n",
+			theirs: "title\nintro\nGo checklist\nfooter\n",
+			want:   "title\nintro\nGo checklist\nfooter\n",
+		},
+		{
+			name:          "conflicting changes",
+			ours:          "title\nintro\nGo checklist\nfooter\n",
+			theirs:        "title\nintro\nPython checklist\nfooter\n",
+			want:          "title\nintro\n<<<<<<< ours\nGo checklist\n=======\nPython checklist\n>>>>>>> theirs\nfooter\n",
+			wantConflicts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := Merge3(base, tt.ours, tt.theirs, "ours", "theirs")
+			if got != tt.want || conflicts != tt.wantConflicts {
+				t.Errorf("Merge3() = %q, %d, want %q, %d", got, conflicts, tt.want, tt.wantConflicts)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing/fstest"
+	"time"
+)
+
+var (
+	// ErrReadOnly is returned when writing below a read-only mount such as a bundle archive
+	ErrReadOnly = errors.New("read-only filesystem")
+	// ErrUnknownArchive is returned for archive files that are neither zip nor gzipped tar
+	ErrUnknownArchive = errors.New("unknown archive format (use .zip, .tar.gz or .tgz)")
+)
+
+// Archive formats, named after their file extension
+const (
+	ARCHIVE_ZIP    = "zip"
+	ARCHIVE_TAR_GZ = "tar.gz"
+)
+
+// ArchiveFile is a regular file written to an archive by WriteArchive
+type ArchiveFile struct {
+	Name    string // slash-separated path inside the archive
+	Data    []byte
+	ModTime time.Time
+}
+
+// ArchiveFormat returns the format of the archive at path based on its extension, or "" if it is not an archive
+func ArchiveFormat(path string) string {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return ARCHIVE_ZIP
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return ARCHIVE_TAR_GZ
+	}
+	return ""
+}
+
+// ArchiveFS implements ReadFS on top of any fs.FS, such as the contents of an archive
+type ArchiveFS struct {
+	fsys fs.FS
+}
+
+// NewArchiveFS creates an ArchiveFS reading from fsys
+func NewArchiveFS(fsys fs.FS) *ArchiveFS {
+	return &ArchiveFS{fsys: fsys}
+}
+
+// OpenArchive reads the zip or gzipped tar archive at path into an ArchiveFS
+func OpenArchive(path string) (*ArchiveFS, error) {
+	format := ArchiveFormat(path)
+	if format == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownArchive, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := ReadArchive(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive %s: %w", path, err)
+	}
+	return archive, nil
+}
+
+// ReadArchive reads an archive in format from data into an ArchiveFS
+func ReadArchive(data []byte, format string) (*ArchiveFS, error) {
+	switch format {
+	case ARCHIVE_ZIP:
+		reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		return NewArchiveFS(reader), nil
+	case ARCHIVE_TAR_GZ:
+		return readTarGz(data)
+	}
+	return nil, ErrUnknownArchive
+}
+
+// readTarGz loads the regular files of a gzipped tar archive into memory.
+// Entries with paths that are not valid fs paths, e.g. ../x, are skipped
+func readTarGz(data []byte) (*ArchiveFS, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := make(fstest.MapFS)
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if header.Typeflag != tar.TypeReg || !fs.ValidPath(name) {
+			continue
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = &fstest.MapFile{
+			Data:    content,
+			Mode:    fs.FileMode(header.Mode).Perm(),
+			ModTime: header.ModTime,
+		}
+	}
+	return NewArchiveFS(files), nil
+}
+
+// WriteArchive writes files to w as an archive in format
+func WriteArchive(w io.Writer, format string, files []ArchiveFile) error {
+	switch format {
+	case ARCHIVE_ZIP:
+		archive := zip.NewWriter(w)
+		for _, file := range files {
+			header := &zip.FileHeader{Name: file.Name, Method: zip.Deflate, Modified: file.ModTime}
+			header.SetMode(0644)
+			writer, err := archive.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+			if _, err := writer.Write(file.Data); err != nil {
+				return err
+			}
+		}
+		return archive.Close()
+	case ARCHIVE_TAR_GZ:
+		gz := gzip.NewWriter(w)
+		archive := tar.NewWriter(gz)
+		for _, file := range files {
+			header := &tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     file.Name,
+				Mode:     0644,
+				Size:     int64(len(file.Data)),
+				ModTime:  file.ModTime,
+			}
+			if err := archive.WriteHeader(header); err != nil {
+				return err
+			}
+			if _, err := archive.Write(file.Data); err != nil {
+				return err
+			}
+		}
+		if err := archive.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	}
+	return ErrUnknownArchive
+}
+
+// Open implements fs.FS
+func (afs *ArchiveFS) Open(name string) (fs.File, error) {
+	return afs.fsys.Open(name)
+}
+
+// ReadFile implements fs.ReadFileFS
+func (afs *ArchiveFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(afs.fsys, name)
+}
+
+// Stat implements fs.StatFS
+func (afs *ArchiveFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(afs.fsys, name)
+}
+
+// ReadDir implements fs.ReadDirFS
+func (afs *ArchiveFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(afs.fsys, name)
+}
@@ -0,0 +1,90 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+)
+
+func TestArchiveFormat(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"team.zip", ARCHIVE_ZIP},
+		{"team.tar.gz", ARCHIVE_TAR_GZ},
+		{"/bundles/Team.TGZ", ARCHIVE_TAR_GZ},
+		{"team.tar", ""},
+		{"review.md", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ArchiveFormat(tt.path); got != tt.want {
+			t.Errorf("ArchiveFormat(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWriteAndReadArchive(t *testing.T) {
+	modTime := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	files := []ArchiveFile{
+		{Name: "review.md", Data: []byte("Review the code"), ModTime: modTime},
+		{Name: "git/commit.md", Data: []byte("Write a commit message"), ModTime: modTime},
+	}
+
+	for _, format := range []string{ARCHIVE_ZIP, ARCHIVE_TAR_GZ} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteArchive(&buf, format, files); err != nil {
+				t.Fatalf("WriteArchive() failed: %v", err)
+			}
+
+			archive, err := ReadArchive(buf.Bytes(), format)
+			if err != nil {
+				t.Fatalf("ReadArchive() failed: %v", err)
+			}
+
+			data, err := archive.ReadFile("git/commit.md")
+			if err != nil || string(data) != "Write a commit message" {
+				t.Errorf("ReadFile() = %q, %v", data, err)
+			}
+			info, err := archive.Stat("review.md")
+			if err != nil || info.Size() != int64(len("Review the code")) || !info.ModTime().Equal(modTime) {
+				t.Errorf("Stat() = %v, %v", info, err)
+			}
+
+			entries, err := archive.ReadDir(".")
+			if err != nil || len(entries) != 2 || entries[0].Name() != "git" || !entries[0].IsDir() {
+				t.Errorf("ReadDir() = %v, %v, want git/ and review.md", entries, err)
+			}
+		})
+	}
+
+	if err := WriteArchive(&bytes.Buffer{}, "rar", files); err != ErrUnknownArchive {
+		t.Errorf("WriteArchive() error = %v, want ErrUnknownArchive", err)
+	}
+}
+
+func TestReadArchiveSkipsUnsafePaths(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for _, name := range []string{"../escape.md", "/etc/passwd.md", "./safe.md"} {
+		archive.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: 2})
+		archive.Write([]byte("hi"))
+	}
+	archive.Close()
+	gz.Close()
+
+	fsys, err := ReadArchive(buf.Bytes(), ARCHIVE_TAR_GZ)
+	if err != nil {
+		t.Fatalf("ReadArchive() failed: %v", err)
+	}
+
+	entries, err := fsys.ReadDir(".")
+	if err != nil || len(entries) != 1 || entries[0].Name() != "safe.md" {
+		t.Errorf("ReadDir() = %v, %v, want only safe.md", entries, err)
+	}
+}
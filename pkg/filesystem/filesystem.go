@@ -1,12 +1,24 @@
 package filesystem
 
 import (
+	"errors"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing/fstest"
+	"time"
 )
 
+// ErrLocked is returned by Lock when another process holds the lock for too long
+var ErrLocked = errors.New("file is locked")
+
+// LOCK_TIMEOUT is how long Lock waits for a lock held by another process
+const LOCK_TIMEOUT = 10 * time.Second
+
+// LOCK_POLL_INTERVAL is how often Lock retries while it waits
+const LOCK_POLL_INTERVAL = 50 * time.Millisecond
+
 // ReadFS combines standard fs interfaces for reading
 type ReadFS interface {
 	fs.ReadFileFS
@@ -20,6 +32,9 @@ type WriteFS interface {
 	MkdirAll(path string, perm os.FileMode) error
 	Remove(path string) error
 	TempFile(dir, pattern string) (*os.File, error)
+	// Lock takes an advisory lock on path for the calling process and returns
+	// the function that releases it. The file itself does not have to exist
+	Lock(path string) (unlock func() error, err error)
 }
 
 // Filesystem combines read and write operations
@@ -28,6 +43,9 @@ type Filesystem interface {
 	WriteFS
 	Getwd() (string, error)
 	UserConfigDir() (string, error)
+	UserDataDir() (string, error)
+	UserCacheDir() (string, error)
+	UserStateDir() (string, error)
 }
 
 // RealFilesystem wraps os.DirFS for reads + standard library for writes
@@ -71,9 +89,51 @@ func (rfs *RealFilesystem) ReadDir(name string) ([]fs.DirEntry, error) {
 	return fs.ReadDir(rfs.readFS, name)
 }
 
-// WriteFile implements WriteFS
+// WriteFile implements WriteFS. The data is written to a temporary file in
+// the same directory that is then renamed over path, so readers see either the
+// old or the new content and never a partially written file. An existing file
+// keeps its mode, and a symlink is kept by writing to the file it points to
 func (rfs *RealFilesystem) WriteFile(path string, data []byte, perm os.FileMode) error {
-	return os.WriteFile(path, data, perm)
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return err
+			}
+			path = target
+			if info, err = os.Stat(path); err != nil {
+				return err
+			}
+		}
+		perm = info.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	temp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name()) // Fails harmlessly once the file is renamed
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
 }
 
 // MkdirAll implements WriteFS
@@ -91,6 +151,25 @@ func (rfs *RealFilesystem) TempFile(dir, pattern string) (*os.File, error) {
 	return os.CreateTemp(dir, pattern)
 }
 
+// Lock implements WriteFS. The lock is held on a lock file in the user cache
+// directory named after the absolute path, so prompt directories stay clean and
+// atomic renames of path do not lose the lock
+func (rfs *RealFilesystem) Lock(path string) (func() error, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	lockDir := filepath.Join(cacheDir, "proompt", "locks")
+	if err := os.MkdirAll(lockDir, 0700); err != nil {
+		return nil, err
+	}
+	return lockFile(filepath.Join(lockDir, lockName(abs)))
+}
+
 // Getwd implements Filesystem
 func (rfs *RealFilesystem) Getwd() (string, error) {
 	return os.Getwd()
@@ -101,11 +180,48 @@ func (rfs *RealFilesystem) UserConfigDir() (string, error) {
 	return os.UserConfigDir()
 }
 
+// UserDataDir implements Filesystem. It returns $XDG_DATA_HOME, or
+// ~/.local/share if that is not set
+func (rfs *RealFilesystem) UserDataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// UserCacheDir implements Filesystem
+func (rfs *RealFilesystem) UserCacheDir() (string, error) {
+	return os.UserCacheDir()
+}
+
+// UserStateDir implements Filesystem. It returns $XDG_STATE_HOME, or
+// ~/.local/state if that is not set
+func (rfs *RealFilesystem) UserStateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state"), nil
+}
+
 // FakeFilesystem uses testing/fstest.MapFS + in-memory writes
 type FakeFilesystem struct {
 	fstest.MapFS
 	cwd           string
 	userConfigDir string
+	userDataDir   string
+	userCacheDir  string
+	userStateDir  string
+
+	mu    sync.Mutex
+	locks map[string]bool
 }
 
 // NewFakeFilesystem creates a new FakeFilesystem
@@ -114,6 +230,9 @@ func NewFakeFilesystem() *FakeFilesystem {
 		MapFS:         make(fstest.MapFS),
 		cwd:           "/",
 		userConfigDir: "/home/user/.config",
+		userDataDir:   "/home/user/.local/share",
+		userCacheDir:  "/home/user/.cache",
+		userStateDir:  "/home/user/.local/state",
 	}
 }
 
@@ -154,6 +273,27 @@ func (ffs *FakeFilesystem) TempFile(dir, pattern string) (*os.File, error) {
 	return nil, nil
 }
 
+// Lock implements WriteFS for FakeFilesystem. Locking a path that is already
+// locked fails with ErrLocked instead of waiting
+func (ffs *FakeFilesystem) Lock(path string) (func() error, error) {
+	ffs.mu.Lock()
+	defer ffs.mu.Unlock()
+	if ffs.locks == nil {
+		ffs.locks = make(map[string]bool)
+	}
+	if ffs.locks[path] {
+		return nil, ErrLocked
+	}
+	ffs.locks[path] = true
+
+	return func() error {
+		ffs.mu.Lock()
+		defer ffs.mu.Unlock()
+		delete(ffs.locks, path)
+		return nil
+	}, nil
+}
+
 // Getwd implements Filesystem for FakeFilesystem
 func (ffs *FakeFilesystem) Getwd() (string, error) {
 	return ffs.cwd, nil
@@ -164,6 +304,21 @@ func (ffs *FakeFilesystem) UserConfigDir() (string, error) {
 	return ffs.userConfigDir, nil
 }
 
+// UserDataDir implements Filesystem for FakeFilesystem
+func (ffs *FakeFilesystem) UserDataDir() (string, error) {
+	return ffs.userDataDir, nil
+}
+
+// UserCacheDir implements Filesystem for FakeFilesystem
+func (ffs *FakeFilesystem) UserCacheDir() (string, error) {
+	return ffs.userCacheDir, nil
+}
+
+// UserStateDir implements Filesystem for FakeFilesystem
+func (ffs *FakeFilesystem) UserStateDir() (string, error) {
+	return ffs.userStateDir, nil
+}
+
 // SetCwd sets the current working directory for FakeFilesystem
 func (ffs *FakeFilesystem) SetCwd(cwd string) {
 	ffs.cwd = cwd
@@ -173,3 +328,18 @@ func (ffs *FakeFilesystem) SetCwd(cwd string) {
 func (ffs *FakeFilesystem) SetUserConfigDir(dir string) {
 	ffs.userConfigDir = dir
 }
+
+// SetUserDataDir sets the user data directory for FakeFilesystem
+func (ffs *FakeFilesystem) SetUserDataDir(dir string) {
+	ffs.userDataDir = dir
+}
+
+// SetUserCacheDir sets the user cache directory for FakeFilesystem
+func (ffs *FakeFilesystem) SetUserCacheDir(dir string) {
+	ffs.userCacheDir = dir
+}
+
+// SetUserStateDir sets the user state directory for FakeFilesystem
+func (ffs *FakeFilesystem) SetUserStateDir(dir string) {
+	ffs.userStateDir = dir
+}
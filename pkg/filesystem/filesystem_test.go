@@ -1,9 +1,11 @@
 package filesystem
 
 import (
+	"errors"
 	"os"
 	"testing"
 	"testing/fstest"
+	"time"
 )
 
 func TestFakeFilesystem_ReadOperations(t *testing.T) {
@@ -181,6 +183,161 @@ func TestFakeFilesystem_Configuration(t *testing.T) {
 			t.Errorf("UserConfigDir() after SetUserConfigDir() = %q, want %q", dir, newDir)
 		}
 	})
+
+	t.Run("SetUserDataDir", func(t *testing.T) {
+		if dir, _ := fs.UserDataDir(); dir != "/home/user/.local/share" {
+			t.Errorf("UserDataDir() = %q, want %q", dir, "/home/user/.local/share")
+		}
+
+		fs.SetUserDataDir("/custom/data")
+		if dir, _ := fs.UserDataDir(); dir != "/custom/data" {
+			t.Errorf("UserDataDir() after SetUserDataDir() = %q, want %q", dir, "/custom/data")
+		}
+	})
+
+	t.Run("SetUserCacheDir", func(t *testing.T) {
+		if dir, _ := fs.UserCacheDir(); dir != "/home/user/.cache" {
+			t.Errorf("UserCacheDir() = %q, want %q", dir, "/home/user/.cache")
+		}
+
+		fs.SetUserCacheDir("/custom/cache")
+		if dir, _ := fs.UserCacheDir(); dir != "/custom/cache" {
+			t.Errorf("UserCacheDir() after SetUserCacheDir() = %q, want %q", dir, "/custom/cache")
+		}
+	})
+
+	t.Run("SetUserStateDir", func(t *testing.T) {
+		if dir, _ := fs.UserStateDir(); dir != "/home/user/.local/state" {
+			t.Errorf("UserStateDir() = %q, want %q", dir, "/home/user/.local/state")
+		}
+
+		fs.SetUserStateDir("/custom/state")
+		if dir, _ := fs.UserStateDir(); dir != "/custom/state" {
+			t.Errorf("UserStateDir() after SetUserStateDir() = %q, want %q", dir, "/custom/state")
+		}
+	})
+}
+
+func TestRealFilesystem_UserDataDir(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/xdg/data")
+
+	dir, err := NewRealFilesystem(".").UserDataDir()
+	if err != nil || dir != "/xdg/data" {
+		t.Errorf("UserDataDir() = %q, %v, want %q", dir, err, "/xdg/data")
+	}
+}
+
+func TestRealFilesystem_WriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	fs := NewRealFilesystem(dir)
+
+	path := dir + "/prompt.md"
+	if err := fs.WriteFile(path, []byte("first"), 0600); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	if err := fs.WriteFile(path, []byte("second"), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "second" {
+		t.Errorf("ReadFile() = %q, %v, want %q", data, err, "second")
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Stat() = %v, %v, want the mode 0600 of the existing file", info, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected only the written file in %s, got %v, %v", dir, entries, err)
+	}
+}
+
+func TestRealFilesystem_WriteFileSymlink(t *testing.T) {
+	dir := t.TempDir()
+	fs := NewRealFilesystem(dir)
+
+	if err := os.WriteFile(dir+"/shared.md", []byte("first"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("shared.md", dir+"/prompt.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile(dir+"/prompt.md", []byte("second"), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	if info, err := os.Lstat(dir + "/prompt.md"); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Lstat() = %v, %v, want the symlink to be kept", info, err)
+	}
+	data, err := os.ReadFile(dir + "/shared.md")
+	if err != nil || string(data) != "second" {
+		t.Errorf("ReadFile() of the link target = %q, %v, want %q", data, err, "second")
+	}
+	if info, err := os.Stat(dir + "/shared.md"); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Stat() = %v, %v, want the mode 0755 of the link target", info, err)
+	}
+}
+
+func TestRealFilesystem_Lock(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	fs := NewRealFilesystem(dir)
+
+	unlock, err := fs.Lock(dir + "/prompt.md")
+	if err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+
+	released := make(chan time.Time, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		released <- time.Now()
+		unlock()
+	}()
+
+	// A second lock on the same path waits until the first one is released
+	unlockAgain, err := fs.Lock(dir + "/prompt.md")
+	if err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+	defer unlockAgain()
+	select {
+	case <-released:
+	default:
+		t.Error("Lock() returned while the path was still locked")
+	}
+
+	if _, err := os.Stat(dir + "/prompt.md"); !os.IsNotExist(err) {
+		t.Errorf("Expected Lock() not to create the locked file, got %v", err)
+	}
+}
+
+func TestFakeFilesystem_Lock(t *testing.T) {
+	fs := NewFakeFilesystem()
+
+	unlock, err := fs.Lock("prompts/review.md")
+	if err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+	if _, err := fs.Lock("prompts/review.md"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Lock() of a locked path error = %v, want ErrLocked", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("unlock() failed: %v", err)
+	}
+	if _, err := fs.Lock("prompts/review.md"); err != nil {
+		t.Errorf("Lock() after unlock() failed: %v", err)
+	}
+}
+
+func TestRealFilesystem_UserStateDir(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/xdg/state")
+
+	dir, err := NewRealFilesystem(".").UserStateDir()
+	if err != nil || dir != "/xdg/state" {
+		t.Errorf("UserStateDir() = %q, %v, want %q", dir, err, "/xdg/state")
+	}
 }
 
 // TestRealFilesystem tests the interface compliance
@@ -0,0 +1,13 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+)
+
+// lockName returns the name of the lock file for the absolute path abs
+func lockName(abs string) string {
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Base(abs) + "-" + hex.EncodeToString(sum[:8]) + ".lock"
+}
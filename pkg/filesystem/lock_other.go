@@ -0,0 +1,32 @@
+//go:build !unix
+
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockFile takes a lock by creating the file at path exclusively, waiting up
+// to LOCK_TIMEOUT for another process to remove it. Unlike flock, the lock
+// file stays behind if the process dies and has to be removed by hand
+func lockFile(path string) (func() error, error) {
+	deadline := time.Now().Add(LOCK_TIMEOUT)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			return func() error { return os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s (remove the file if no proompt process is running)", ErrLocked, path)
+		}
+		time.Sleep(LOCK_POLL_INTERVAL)
+	}
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// lockFile takes an exclusive flock(2) lock on the file at path, waiting up
+// to LOCK_TIMEOUT. The kernel releases the lock if the process dies
+func lockFile(path string) (func() error, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(LOCK_TIMEOUT)
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if err != syscall.EWOULDBLOCK || time.Now().After(deadline) {
+			file.Close()
+			if err == syscall.EWOULDBLOCK {
+				return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+			}
+			return nil, err
+		}
+		time.Sleep(LOCK_POLL_INTERVAL)
+	}
+
+	return func() error {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		return file.Close()
+	}, nil
+}
@@ -0,0 +1,100 @@
+package filesystem
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// MountFS is a Filesystem that serves the directories mounted with Mount
+// from read-only filesystems, such as bundle archives, and all other paths
+// from the Filesystem it wraps. Writes below a mount fail with ErrReadOnly
+type MountFS struct {
+	Filesystem
+	mounts map[string]ReadFS
+}
+
+// NewMountFS creates a MountFS on top of base
+func NewMountFS(base Filesystem) *MountFS {
+	return &MountFS{
+		Filesystem: base,
+		mounts:     make(map[string]ReadFS),
+	}
+}
+
+// Mount serves the contents of fsys at dir. dir may be the path of the archive
+// file itself, which then reads as a directory
+func (mfs *MountFS) Mount(dir string, fsys ReadFS) {
+	mfs.mounts[path.Clean(dir)] = fsys
+}
+
+// resolve returns the mount containing name and the path of name inside it
+func (mfs *MountFS) resolve(name string) (ReadFS, string, bool) {
+	name = path.Clean(name)
+	for dir, fsys := range mfs.mounts {
+		if name == dir {
+			return fsys, ".", true
+		}
+		if rest, found := strings.CutPrefix(name, dir+"/"); found {
+			return fsys, rest, true
+		}
+	}
+	return nil, "", false
+}
+
+// Open implements fs.FS
+func (mfs *MountFS) Open(name string) (fs.File, error) {
+	if fsys, rest, ok := mfs.resolve(name); ok {
+		return fsys.Open(rest)
+	}
+	return mfs.Filesystem.Open(name)
+}
+
+// ReadFile implements fs.ReadFileFS
+func (mfs *MountFS) ReadFile(name string) ([]byte, error) {
+	if fsys, rest, ok := mfs.resolve(name); ok {
+		return fsys.ReadFile(rest)
+	}
+	return mfs.Filesystem.ReadFile(name)
+}
+
+// Stat implements fs.StatFS
+func (mfs *MountFS) Stat(name string) (fs.FileInfo, error) {
+	if fsys, rest, ok := mfs.resolve(name); ok {
+		return fsys.Stat(rest)
+	}
+	return mfs.Filesystem.Stat(name)
+}
+
+// ReadDir implements fs.ReadDirFS
+func (mfs *MountFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if fsys, rest, ok := mfs.resolve(name); ok {
+		return fsys.ReadDir(rest)
+	}
+	return mfs.Filesystem.ReadDir(name)
+}
+
+// WriteFile implements WriteFS
+func (mfs *MountFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if _, _, ok := mfs.resolve(name); ok {
+		return &fs.PathError{Op: "write", Path: name, Err: ErrReadOnly}
+	}
+	return mfs.Filesystem.WriteFile(name, data, perm)
+}
+
+// MkdirAll implements WriteFS
+func (mfs *MountFS) MkdirAll(name string, perm os.FileMode) error {
+	if _, _, ok := mfs.resolve(name); ok {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: ErrReadOnly}
+	}
+	return mfs.Filesystem.MkdirAll(name, perm)
+}
+
+// Remove implements WriteFS
+func (mfs *MountFS) Remove(name string) error {
+	if _, _, ok := mfs.resolve(name); ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+	}
+	return mfs.Filesystem.Remove(name)
+}
@@ -0,0 +1,60 @@
+package filesystem
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMountFS(t *testing.T) {
+	base := NewFakeFilesystem()
+	base.MapFS["prompts/local.md"] = &fstest.MapFile{Data: []byte("local"), Mode: 0644}
+
+	fs := NewMountFS(base)
+	fs.Mount("/bundles/team.zip", NewArchiveFS(fstest.MapFS{
+		"review.md": &fstest.MapFile{Data: []byte("from bundle"), Mode: 0644},
+	}))
+
+	t.Run("reads below the mount", func(t *testing.T) {
+		data, err := fs.ReadFile("/bundles/team.zip/review.md")
+		if err != nil || string(data) != "from bundle" {
+			t.Errorf("ReadFile() = %q, %v", data, err)
+		}
+		info, err := fs.Stat("/bundles/team.zip")
+		if err != nil || !info.IsDir() {
+			t.Errorf("Stat() of the mount point = %v, %v, want a directory", info, err)
+		}
+		entries, err := fs.ReadDir("/bundles/team.zip/")
+		if err != nil || len(entries) != 1 {
+			t.Errorf("ReadDir() = %v, %v", entries, err)
+		}
+	})
+
+	t.Run("reads elsewhere", func(t *testing.T) {
+		data, err := fs.ReadFile("prompts/local.md")
+		if err != nil || string(data) != "local" {
+			t.Errorf("ReadFile() = %q, %v", data, err)
+		}
+		if _, err := fs.Stat("/bundles/team.zipper/review.md"); err == nil {
+			t.Error("Stat() of a sibling of the mount point should not read the mount")
+		}
+	})
+
+	t.Run("writes", func(t *testing.T) {
+		if err := fs.WriteFile("/bundles/team.zip/new.md", []byte("x"), 0644); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("WriteFile() below the mount error = %v, want ErrReadOnly", err)
+		}
+		if err := fs.Remove("/bundles/team.zip/review.md"); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("Remove() below the mount error = %v, want ErrReadOnly", err)
+		}
+		if err := fs.MkdirAll("/bundles/team.zip/git", 0755); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("MkdirAll() below the mount error = %v, want ErrReadOnly", err)
+		}
+		if err := fs.WriteFile("prompts/new.md", []byte("x"), 0644); err != nil {
+			t.Errorf("WriteFile() elsewhere failed: %v", err)
+		}
+		if _, ok := base.MapFS["prompts/new.md"]; !ok {
+			t.Error("Expected WriteFile() elsewhere to write to the base filesystem")
+		}
+	})
+}
@@ -15,9 +15,23 @@ type Picker interface {
 
 // PickerItem represents an item that can be selected
 type PickerItem struct {
-	Name   string
-	Source string // "directory", "project", "project-local", "user"
-	Path   string
+	Name        string
+	Source      string // "directory", "project", "project-local", "user"
+	Path        string
+	Description string
+	Tags        []string
+}
+
+// Line formats an item as a single line of picker input
+func (item PickerItem) Line() string {
+	line := fmt.Sprintf("%s (%s)", item.Name, item.Source)
+	if description := strings.Join(strings.Fields(item.Description), " "); description != "" {
+		line += " - " + description
+	}
+	for _, tag := range item.Tags {
+		line += " #" + tag
+	}
+	return line
 }
 
 // RealPicker uses an external picker tool
@@ -41,7 +55,7 @@ func (p *RealPicker) Pick(items []PickerItem) (PickerItem, error) {
 	// Create input for picker
 	var input strings.Builder
 	for _, item := range items {
-		fmt.Fprintf(&input, "%s (%s)\n", item.Name, item.Source)
+		fmt.Fprintln(&input, item.Line())
 	}
 
 	// Execute picker command
@@ -62,8 +76,7 @@ func (p *RealPicker) Pick(items []PickerItem) (PickerItem, error) {
 
 	// Find the matching item
 	for _, item := range items {
-		expectedLine := fmt.Sprintf("%s (%s)", item.Name, item.Source)
-		if selected == expectedLine {
+		if selected == item.Line() {
 			return item, nil
 		}
 	}
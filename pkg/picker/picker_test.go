@@ -124,3 +124,19 @@ func TestNewRealPicker(t *testing.T) {
 		t.Errorf("expected command %s, got %s", command, picker.Command)
 	}
 }
+
+func TestPickerItemLine(t *testing.T) {
+	tests := []struct {
+		item PickerItem
+		want string
+	}{
+		{PickerItem{Name: "review", Source: "user"}, "review (user)"},
+		{PickerItem{Name: "review", Source: "user", Description: "Review\ncode", Tags: []string{"go", "pr"}}, "review (user) - Review code #go #pr"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.item.Line(); got != tt.want {
+			t.Errorf("Line() = %q, want %q", got, tt.want)
+		}
+	}
+}
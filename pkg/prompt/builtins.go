@@ -0,0 +1,98 @@
+package prompt
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+// Built-in contextual variables, filled in automatically when a prompt is rendered
+const (
+	BUILTIN_DATE          = "proompt.date" // 2006-01-02
+	BUILTIN_TIME          = "proompt.time" // 15:04
+	BUILTIN_CWD           = "proompt.cwd"
+	BUILTIN_OS            = "proompt.os"
+	BUILTIN_ARCH          = "proompt.arch"
+	BUILTIN_PROMPT_NAME   = "prompt.name"
+	BUILTIN_PROMPT_SOURCE = "prompt.source"
+	BUILTIN_PROMPT_PATH   = "prompt.path"
+	BUILTIN_GIT_ROOT      = "git.root"
+	BUILTIN_GIT_BRANCH    = "git.branch"
+	BUILTIN_PROJECT_ROOT  = "project.root"
+	BUILTIN_PROJECT_NAME  = "project.name"
+)
+
+// builtinNamespaces are reserved; placeholders in them are never offered for editing
+var builtinNamespaces = []string{"proompt", "prompt", "git", "project"}
+
+// IsBuiltin reports whether name belongs to a reserved namespace such as git.branch
+func IsBuiltin(name string) bool {
+	namespace, _, dotted := strings.Cut(name, ".")
+	if !dotted {
+		return false
+	}
+	for _, reserved := range builtinNamespaces {
+		if namespace == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// BuiltinValues computes the built-in contextual variables for rendering info
+// at time now. Project and git values come from fs using the same upward search
+// as the prompt hierarchy; values that cannot be determined are left out so
+// that defaults such as ${git.branch:-main} apply
+func BuiltinValues(fs filesystem.Filesystem, info *PromptInfo, now time.Time) map[string]string {
+	values := map[string]string{
+		BUILTIN_DATE: now.Format("2006-01-02"),
+		BUILTIN_TIME: now.Format("15:04"),
+		BUILTIN_OS:   runtime.GOOS,
+		BUILTIN_ARCH: runtime.GOARCH,
+	}
+
+	if info != nil {
+		values[BUILTIN_PROMPT_NAME] = info.Name
+		values[BUILTIN_PROMPT_SOURCE] = info.Source
+		values[BUILTIN_PROMPT_PATH] = info.Path
+	}
+
+	if cwd, err := fs.Getwd(); err == nil {
+		values[BUILTIN_CWD] = cwd
+	}
+
+	if root, err := FindProjectRoot(fs); err == nil {
+		values[BUILTIN_PROJECT_ROOT] = root
+		values[BUILTIN_PROJECT_NAME] = filepath.Base(root)
+	}
+
+	if root, ok, _ := findUpward(fs, func(dir string) bool { return isDir(fs, filepath.Join(dir, ".git")) }); ok {
+		values[BUILTIN_GIT_ROOT] = root
+		if branch := gitBranch(fs, root); branch != "" {
+			values[BUILTIN_GIT_BRANCH] = branch
+		}
+	}
+
+	return values
+}
+
+// gitBranch reads the current branch from .git/HEAD; a detached HEAD yields the
+// abbreviated commit hash
+func gitBranch(fs filesystem.Filesystem, root string) string {
+	data, err := fs.ReadFile(filepath.Join(root, ".git", "HEAD"))
+	if err != nil {
+		return ""
+	}
+
+	head := strings.TrimSpace(string(data))
+	if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+		return strings.TrimPrefix(ref, "refs/heads/")
+	}
+	if len(head) > 12 {
+		return head[:12]
+	}
+	return head
+}
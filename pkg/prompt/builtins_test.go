@@ -0,0 +1,91 @@
+package prompt
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+func TestIsBuiltin(t *testing.T) {
+	tests := map[string]bool{
+		"git.branch":   true,
+		"proompt.date": true,
+		"project.name": true,
+		"prompt.name":  true,
+		"git":          false,
+		"NAME":         false,
+		"user.name":    false,
+	}
+	for name, expected := range tests {
+		if got := IsBuiltin(name); got != expected {
+			t.Errorf("IsBuiltin(%q) = %v, want %v", name, got, expected)
+		}
+	}
+}
+
+func TestBuiltinValues(t *testing.T) {
+	fs := filesystem.NewFakeFilesystem()
+	fs.MapFS["work/repo/.git/HEAD"] = &fstest.MapFile{Data: []byte("ref: refs/heads/feature/x\n")}
+	fs.MapFS["work/repo/src/main.go"] = &fstest.MapFile{Data: []byte("package main")}
+	fs.SetCwd("work/repo/src")
+
+	info := &PromptInfo{Name: "review", Source: "project", Path: "work/repo/prompts/review.md"}
+	now := time.Date(2024, 3, 9, 14, 5, 0, 0, time.UTC)
+
+	values := BuiltinValues(fs, info, now)
+	expected := map[string]string{
+		BUILTIN_DATE:          "2024-03-09",
+		BUILTIN_TIME:          "14:05",
+		BUILTIN_CWD:           "work/repo/src",
+		BUILTIN_OS:            runtime.GOOS,
+		BUILTIN_ARCH:          runtime.GOARCH,
+		BUILTIN_PROMPT_NAME:   "review",
+		BUILTIN_PROMPT_SOURCE: "project",
+		BUILTIN_PROMPT_PATH:   "work/repo/prompts/review.md",
+		BUILTIN_GIT_ROOT:      "work/repo",
+		BUILTIN_GIT_BRANCH:    "feature/x",
+		BUILTIN_PROJECT_ROOT:  "work/repo",
+		BUILTIN_PROJECT_NAME:  "repo",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("BuiltinValues() = %v, want %v", values, expected)
+	}
+
+	// Outside a repository the git values are left out so defaults apply
+	fs.SetCwd("elsewhere")
+	values = BuiltinValues(fs, nil, now)
+	if _, ok := values[BUILTIN_GIT_BRANCH]; ok {
+		t.Errorf("BuiltinValues() outside a repository set git.branch = %q", values[BUILTIN_GIT_BRANCH])
+	}
+
+	result, err := NewDefaultParser().SubstitutePlaceholders("On ${git.branch:-main} at ${proompt.date}", StringValues(values))
+	if err != nil || result != "On main at 2024-03-09" {
+		t.Errorf("SubstitutePlaceholders() = %q, %v", result, err)
+	}
+}
+
+func TestBuiltinsAreNotVariables(t *testing.T) {
+	content := "Branch ${git.branch} in ${project.name}: ${TITLE:-Fix on ${git.branch}}"
+	placeholders, err := NewDefaultParser().ParsePlaceholders(content)
+	if err != nil {
+		t.Fatalf("ParsePlaceholders() error = %v", err)
+	}
+	expected := []Placeholder{{Name: "TITLE", DefaultValue: "Fix on ${git.branch}", HasDefault: true, Operators: []string{OP_DEFAULT}, DependsOn: []string{"git.branch"}}}
+	if !reflect.DeepEqual(placeholders, expected) {
+		t.Errorf("ParsePlaceholders() = %+v, want %+v", placeholders, expected)
+	}
+
+	engine := NewGoTemplateEngine()
+	placeholders, err = engine.Variables("{{.git.branch}} {{.NAME}}")
+	if err != nil || len(placeholders) != 1 || placeholders[0].Name != "NAME" {
+		t.Errorf("GoTemplateEngine.Variables() = %+v, %v", placeholders, err)
+	}
+	result, err := engine.Render("{{.git.branch}} {{.NAME}}", map[string]any{"git.branch": "main", "NAME": "x"})
+	if err != nil || result != "main x" {
+		t.Errorf("GoTemplateEngine.Render() = %q, %v", result, err)
+	}
+}
@@ -0,0 +1,128 @@
+package prompt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+var (
+	// ErrInvalidBundle is returned when a bundle has no readable manifest or lists invalid prompts
+	ErrInvalidBundle = errors.New("invalid bundle")
+	// ErrChecksumMismatch is returned when a prompt in a bundle does not match its manifest checksum
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+const (
+	// BUNDLE_VERSION is the manifest version written by WriteBundle; newer bundles are rejected
+	BUNDLE_VERSION = 1
+	// BUNDLE_MANIFEST is the path of the manifest inside a bundle
+	BUNDLE_MANIFEST = "proompt-bundle.json"
+	// BUNDLE_SOURCE is the location type of bundles mounted as prompt locations
+	BUNDLE_SOURCE = "bundle"
+)
+
+// Manifest describes the prompts of a bundle
+type Manifest struct {
+	Version int            `json:"version"`
+	Created time.Time      `json:"created"`
+	Prompts []BundlePrompt `json:"prompts"`
+}
+
+// BundlePrompt is the manifest entry of one prompt in a bundle
+type BundlePrompt struct {
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`   // relative to the bundle root
+	Source      string   `json:"source"` // the level the prompt was packed from
+	SHA256      string   `json:"sha256"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Version     string   `json:"version,omitempty"`
+}
+
+// WriteBundle writes prompts and their manifest to w as an archive in format,
+// one of the filesystem.ARCHIVE_* formats. Prompts are stored at the root of
+// the archive under their names, so a bundle can be mounted as a prompt location
+func WriteBundle(w io.Writer, format string, prompts []PromptInfo, now time.Time) (*Manifest, error) {
+	manifest := &Manifest{Version: BUNDLE_VERSION, Created: now.UTC()}
+	files := make([]filesystem.ArchiveFile, 0, len(prompts)+1)
+	seen := make(map[string]string, len(prompts))
+	for _, info := range prompts {
+		if source, ok := seen[info.Name]; ok {
+			return nil, fmt.Errorf("%w: %s is packed from both %s and %s", ErrInvalidBundle, info.Name, source, info.Source)
+		}
+		seen[info.Name] = info.Source
+
+		sum := sha256.Sum256([]byte(info.Content))
+		entry := BundlePrompt{
+			Name:        info.Name,
+			Path:        info.Name + Extension(info.Path),
+			Source:      info.Source,
+			SHA256:      hex.EncodeToString(sum[:]),
+			Description: info.Metadata.Description,
+			Tags:        info.Metadata.Tags,
+			Author:      info.Metadata.Author,
+			Version:     info.Metadata.Version,
+		}
+		manifest.Prompts = append(manifest.Prompts, entry)
+		files = append(files, filesystem.ArchiveFile{Name: entry.Path, Data: []byte(info.Content), ModTime: manifest.Created})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files = append([]filesystem.ArchiveFile{{Name: BUNDLE_MANIFEST, Data: data, ModTime: manifest.Created}}, files...)
+
+	if err := filesystem.WriteArchive(w, format, files); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ReadBundle reads the manifest of the bundle in fsys and the prompts it
+// lists, verifying their checksums. The prompts have BUNDLE_SOURCE as source
+// and their path inside the bundle
+func ReadBundle(fsys filesystem.ReadFS) (*Manifest, []PromptInfo, error) {
+	data, err := fsys.ReadFile(BUNDLE_MANIFEST)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s is missing", ErrInvalidBundle, BUNDLE_MANIFEST)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if manifest.Version > BUNDLE_VERSION {
+		return nil, nil, fmt.Errorf("%w: manifest version %d is newer than %d", ErrInvalidBundle, manifest.Version, BUNDLE_VERSION)
+	}
+
+	prompts := make([]PromptInfo, 0, len(manifest.Prompts))
+	for _, entry := range manifest.Prompts {
+		if err := validateName(entry.Name); err != nil || !fs.ValidPath(entry.Path) || !isPromptFile(entry.Path) {
+			return nil, nil, fmt.Errorf("%w: invalid prompt %q at %q", ErrInvalidBundle, entry.Name, entry.Path)
+		}
+		content, err := fsys.ReadFile(entry.Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s is missing", ErrInvalidBundle, entry.Path)
+		}
+		sum := sha256.Sum256(content)
+		if hex.EncodeToString(sum[:]) != entry.SHA256 {
+			return nil, nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, entry.Path)
+		}
+
+		info := PromptInfo{Name: entry.Name, Content: string(content), Source: BUNDLE_SOURCE, Path: entry.Path}
+		if frontmatter, _, err := ParsePrompt(&info); err == nil {
+			info.Metadata = frontmatter.Metadata
+		}
+		prompts = append(prompts, info)
+	}
+	return &manifest, prompts, nil
+}
@@ -0,0 +1,112 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+func TestWriteAndReadBundle(t *testing.T) {
+	prompts := []PromptInfo{
+		{
+			Name:     "review",
+			Content:  "---\ndescription: Review code\ntags: [code]\n---\nReview ${FILE}",
+			Source:   "user",
+			Path:     "/home/user/.config/proompt/prompts/review.md",
+			Metadata: Metadata{Description: "Review code", Tags: []string{"code"}},
+		},
+		{
+			Name:    "chat/plan",
+			Content: "messages: []\n",
+			Source:  "project",
+			Path:    "/project/prompts/chat/plan.prompt.yaml",
+		},
+	}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	manifest, err := WriteBundle(&buf, filesystem.ARCHIVE_TAR_GZ, prompts, now)
+	if err != nil {
+		t.Fatalf("WriteBundle() failed: %v", err)
+	}
+	if len(manifest.Prompts) != 2 || manifest.Prompts[1].Path != "chat/plan.prompt.yaml" || manifest.Prompts[0].Description != "Review code" {
+		t.Errorf("WriteBundle() manifest = %+v", manifest)
+	}
+
+	archive, err := filesystem.ReadArchive(buf.Bytes(), filesystem.ARCHIVE_TAR_GZ)
+	if err != nil {
+		t.Fatalf("ReadArchive() failed: %v", err)
+	}
+	read, unpacked, err := ReadBundle(archive)
+	if err != nil {
+		t.Fatalf("ReadBundle() failed: %v", err)
+	}
+	if read.Version != BUNDLE_VERSION || !read.Created.Equal(now) {
+		t.Errorf("ReadBundle() manifest = %+v", read)
+	}
+	if len(unpacked) != 2 {
+		t.Fatalf("ReadBundle() returned %d prompts, want 2", len(unpacked))
+	}
+	review := unpacked[0]
+	if review.Name != "review" || review.Source != BUNDLE_SOURCE || review.Path != "review.md" || review.Content != prompts[0].Content || review.Metadata.Description != "Review code" {
+		t.Errorf("ReadBundle() prompt = %+v", review)
+	}
+
+	if _, err := WriteBundle(&buf, filesystem.ARCHIVE_ZIP, append(prompts, PromptInfo{Name: "review", Source: "project", Path: "review.md"}), now); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("WriteBundle() with a duplicate name error = %v, want ErrInvalidBundle", err)
+	}
+}
+
+func TestReadBundleErrors(t *testing.T) {
+	manifest := `{"version": 1, "prompts": [{"name": "review", "path": "review.md", "sha256": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"}]}`
+
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr error
+	}{
+		{
+			name:    "valid",
+			files:   fstest.MapFS{BUNDLE_MANIFEST: {Data: []byte(manifest)}, "review.md": {Data: []byte("test")}},
+			wantErr: nil,
+		},
+		{
+			name:    "missing manifest",
+			files:   fstest.MapFS{"review.md": {Data: []byte("test")}},
+			wantErr: ErrInvalidBundle,
+		},
+		{
+			name:    "modified prompt",
+			files:   fstest.MapFS{BUNDLE_MANIFEST: {Data: []byte(manifest)}, "review.md": {Data: []byte("tampered")}},
+			wantErr: ErrChecksumMismatch,
+		},
+		{
+			name:    "missing prompt",
+			files:   fstest.MapFS{BUNDLE_MANIFEST: {Data: []byte(manifest)}},
+			wantErr: ErrInvalidBundle,
+		},
+		{
+			name:    "unsafe path",
+			files:   fstest.MapFS{BUNDLE_MANIFEST: {Data: []byte(`{"version": 1, "prompts": [{"name": "x", "path": "../x.md"}]}`)}},
+			wantErr: ErrInvalidBundle,
+		},
+		{
+			name:    "newer version",
+			files:   fstest.MapFS{BUNDLE_MANIFEST: {Data: []byte(`{"version": 2}`)}},
+			wantErr: ErrInvalidBundle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ReadBundle(filesystem.NewArchiveFS(tt.files))
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("ReadBundle() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
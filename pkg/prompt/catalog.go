@@ -0,0 +1,209 @@
+package prompt
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SCAN_CONCURRENCY bounds the directories read and the files loaded at the same time
+const SCAN_CONCURRENCY = 16
+
+// catalogEntry is a prompt file found by scan
+type catalogEntry struct {
+	PromptInfo
+	absPath string
+	info    fs.FileInfo
+}
+
+// scannedFile is a prompt file found by walk, relative to the location it was found in
+type scannedFile struct {
+	name string
+	info fs.FileInfo
+}
+
+// Catalog returns the prompts of all locations in priority order, including
+// prompts shadowed by a prompt of the same name at a higher level, which have
+// ShadowedBy set. Unlike ListAll it does not return the prompts' content; the
+// metadata is read from the Index if the manager has one and the file did not change
+func (m *DefaultManager) Catalog() ([]PromptInfo, error) {
+	entries, roots, err := m.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	forEach(len(entries), func(i int) {
+		entry := &entries[i]
+		if m.Index != nil {
+			if metadata, ok := m.Index.Lookup(entry.absPath, entry.info); ok {
+				entry.Metadata = metadata
+				return
+			}
+		}
+		if err := m.load(&entry.PromptInfo); err != nil {
+			entry.info = nil // Skip files that can't be read
+			return
+		}
+		entry.Content = ""
+		if m.Index != nil {
+			m.Index.Update(entry.absPath, entry.info, entry.Metadata)
+		}
+	})
+
+	var prompts []PromptInfo
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.info != nil {
+			prompts = append(prompts, entry.PromptInfo)
+			seen[entry.absPath] = true
+		}
+	}
+
+	if m.Index != nil {
+		m.Index.Retain(roots, seen)
+		m.Index.Save()
+	}
+	return markShadowed(prompts), nil
+}
+
+// scan finds the prompt files of all locations in priority order without
+// reading them. Locations and their subdirectories are read in parallel. It
+// also returns the absolute paths of the locations
+func (m *DefaultManager) scan() ([]catalogEntry, []string, error) {
+	locations, err := m.Resolver.GetPromptPaths()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := make([][]scannedFile, len(locations))
+	limit := make(chan struct{}, SCAN_CONCURRENCY)
+	forEach(len(locations), func(i int) {
+		found[i] = m.walk(locations[i].Path, "", limit)
+	})
+
+	var entries []catalogEntry
+	roots := make([]string, 0, len(locations))
+	seenPaths := make(map[string]bool) // Track absolute paths to avoid duplicates
+	for i, location := range locations {
+		roots = append(roots, absolutePath(location.Path))
+
+		for _, file := range found[i] {
+			fullPath := location.Path + "/" + file.name
+			absPath := absolutePath(fullPath)
+
+			// Skip if we've already seen this file path
+			if seenPaths[absPath] {
+				continue
+			}
+			seenPaths[absPath] = true
+
+			entries = append(entries, catalogEntry{
+				PromptInfo: PromptInfo{
+					Name:   removeExtension(file.name),
+					Source: location.Type,
+					Path:   fullPath,
+				},
+				absPath: absPath,
+				info:    file.info,
+			})
+		}
+	}
+
+	return entries, roots, nil
+}
+
+// walk returns the prompt files below dir as slash-separated paths relative
+// to dir, in directory order. Subdirectories are read in parallel; hidden
+// directories are skipped. limit bounds the directories read at the same time
+func (m *DefaultManager) walk(dir, prefix string, limit chan struct{}) []scannedFile {
+	limit <- struct{}{}
+	entries, err := m.Filesystem.ReadDir(dir)
+	<-limit
+	if err != nil {
+		return nil // Skip directories that can't be read
+	}
+
+	found := make([][]scannedFile, len(entries))
+	var wg sync.WaitGroup
+	for i, entry := range entries {
+		switch {
+		case entry.IsDir():
+			if strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				found[i] = m.walk(dir+"/"+entry.Name(), prefix+entry.Name()+"/", limit)
+			}()
+		case isPromptFile(entry.Name()):
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			found[i] = []scannedFile{{name: prefix + entry.Name(), info: info}}
+		}
+	}
+	wg.Wait()
+
+	return slices.Concat(found...)
+}
+
+// load reads the content of info and the metadata of its frontmatter. An
+// invalid frontmatter leaves the metadata empty; it is reported when the prompt is used
+func (m *DefaultManager) load(info *PromptInfo) error {
+	content, err := m.Filesystem.ReadFile(info.Path)
+	if err != nil {
+		return err
+	}
+
+	info.Content = string(content)
+	info.Metadata = Metadata{}
+	if frontmatter, _, err := ParsePrompt(info); err == nil {
+		info.Metadata = frontmatter.Metadata
+	}
+	return nil
+}
+
+// markShadowed sets ShadowedBy on every prompt that a prompt of the same name listed before it overrides
+func markShadowed(prompts []PromptInfo) []PromptInfo {
+	visible := make(map[string]string) // prompt name -> source it is listed from
+	for i, prompt := range prompts {
+		if source, seen := visible[prompt.Name]; seen {
+			prompts[i].ShadowedBy = source
+			continue
+		}
+		visible[prompt.Name] = prompt.Source
+	}
+	return prompts
+}
+
+// forEach calls fn for the numbers 0 to n-1 on up to SCAN_CONCURRENCY goroutines
+func forEach(n int, fn func(i int)) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(n, SCAN_CONCURRENCY) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// absolutePath returns the absolute form of path, or path itself if it cannot be determined
+func absolutePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
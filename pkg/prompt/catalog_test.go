@@ -0,0 +1,155 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+func TestDefaultManagerCatalog(t *testing.T) {
+	modTime := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	fs := filesystem.NewFakeFilesystem()
+	fs.MapFS["prompts/review.md"] = &fstest.MapFile{Data: []byte("---\ndescription: Review\n---\nBody"), ModTime: modTime}
+	fs.MapFS["prompts/git/commit.md"] = &fstest.MapFile{Data: []byte("Commit"), ModTime: modTime}
+	fs.MapFS["user/prompts/review.md"] = &fstest.MapFile{Data: []byte("User review"), ModTime: modTime}
+
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{
+		{Type: "directory", Path: "prompts"},
+		{Type: "user", Path: "user/prompts"},
+	}
+	manager := NewDefaultManager(fs, resolver)
+	manager.Index = &Index{Filesystem: fs, Path: "cache/index.json"}
+
+	catalog := func() map[string]PromptInfo {
+		t.Helper()
+		prompts, err := manager.Catalog()
+		if err != nil {
+			t.Fatalf("Catalog() failed: %v", err)
+		}
+		byKey := make(map[string]PromptInfo)
+		for _, prompt := range prompts {
+			if prompt.Content != "" {
+				t.Errorf("Catalog() returned the content of %s", prompt.Path)
+			}
+			byKey[prompt.Source+":"+prompt.Name] = prompt
+		}
+		return byKey
+	}
+
+	prompts := catalog()
+	if len(prompts) != 3 || prompts["directory:review"].Metadata.Description != "Review" || prompts["user:review"].ShadowedBy != "directory" {
+		t.Fatalf("Catalog() = %+v", prompts)
+	}
+	if _, err := fs.ReadFile("cache/index.json"); err != nil {
+		t.Fatalf("Expected the index to be saved: %v", err)
+	}
+
+	// A change that keeps the modification time and size is not noticed, which shows the index is used
+	fs.MapFS["prompts/review.md"].Data = []byte("---\ndescription: Cached\n---\nBody")
+	if prompts := catalog(); prompts["directory:review"].Metadata.Description != "Review" {
+		t.Errorf("Expected the cached metadata, got %+v", prompts["directory:review"].Metadata)
+	}
+
+	fs.MapFS["prompts/review.md"].ModTime = modTime.Add(time.Second)
+	if prompts := catalog(); prompts["directory:review"].Metadata.Description != "Cached" {
+		t.Errorf("Expected the metadata to be read again, got %+v", prompts["directory:review"].Metadata)
+	}
+
+	// A new index instance reads the saved entries; removed files are dropped from it
+	delete(fs.MapFS, "prompts/git/commit.md")
+	catalog()
+	index := &Index{Filesystem: fs, Path: "cache/index.json"}
+	if _, ok := index.Lookup(absolutePath("prompts/review.md"), fileInfo(t, fs, "prompts/review.md")); !ok {
+		t.Error("Expected the saved index to contain prompts/review.md")
+	}
+	if len(index.entries) != 2 {
+		t.Errorf("Expected the removed prompt to be dropped from the index, got %v", index.entries)
+	}
+}
+
+// fileInfo returns the FileInfo of path in fs
+func fileInfo(t *testing.T, fs filesystem.Filesystem, path string) os.FileInfo {
+	t.Helper()
+	info, err := fs.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+// BENCHMARK_PROMPTS is the size of the prompt library used by the benchmarks
+const BENCHMARK_PROMPTS = 10000
+
+// newBenchmarkManager creates a manager over BENCHMARK_PROMPTS prompts in 100 folders of a real directory
+func newBenchmarkManager(b *testing.B) *DefaultManager {
+	b.Helper()
+	dir := b.TempDir()
+	for i := range BENCHMARK_PROMPTS {
+		folder := filepath.Join(dir, "prompts", fmt.Sprintf("folder%02d", i%100))
+		if i < 100 {
+			if err := os.MkdirAll(folder, 0755); err != nil {
+				b.Fatal(err)
+			}
+		}
+		content := fmt.Sprintf("---\ndescription: Prompt %d\ntags: [bench]\n---\nReview ${CODE} for issue %d.\n", i, i)
+		if err := os.WriteFile(filepath.Join(folder, fmt.Sprintf("prompt%05d.md", i)), []byte(content), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{{Type: "user", Path: dir + "/prompts"}}
+	return NewDefaultManager(filesystem.NewRealFilesystem(dir), resolver)
+}
+
+func BenchmarkGet(b *testing.B) {
+	manager := newBenchmarkManager(b)
+	b.ResetTimer()
+	for range b.N {
+		if _, err := manager.Get("folder42/prompt05042"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListAll(b *testing.B) {
+	manager := newBenchmarkManager(b)
+	b.ResetTimer()
+	for range b.N {
+		if prompts, err := manager.ListAll(); err != nil || len(prompts) != BENCHMARK_PROMPTS {
+			b.Fatalf("ListAll() = %d prompts, %v", len(prompts), err)
+		}
+	}
+}
+
+func BenchmarkCatalogWithoutIndex(b *testing.B) {
+	manager := newBenchmarkManager(b)
+	b.ResetTimer()
+	for range b.N {
+		if prompts, err := manager.Catalog(); err != nil || len(prompts) != BENCHMARK_PROMPTS {
+			b.Fatalf("Catalog() = %d prompts, %v", len(prompts), err)
+		}
+	}
+}
+
+func BenchmarkCatalogWithIndex(b *testing.B) {
+	manager := newBenchmarkManager(b)
+	manager.Index = &Index{Filesystem: manager.Filesystem, Path: b.TempDir() + "/index.json"}
+	if _, err := manager.Catalog(); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for range b.N {
+		// A fresh Index reads the saved file, as a new proompt process would
+		manager.Index = &Index{Filesystem: manager.Filesystem, Path: manager.Index.Path}
+		if prompts, err := manager.Catalog(); err != nil || len(prompts) != BENCHMARK_PROMPTS {
+			b.Fatalf("Catalog() = %d prompts, %v", len(prompts), err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CHAT_EXTENSION marks prompt files that hold a list of role-tagged messages
+const CHAT_EXTENSION = ".prompt.yaml"
+
+// Roles of chat messages
+const (
+	ROLE_SYSTEM    = "system"
+	ROLE_USER      = "user"
+	ROLE_ASSISTANT = "assistant"
+)
+
+// Output formats for rendered prompts
+const (
+	FORMAT_MARKDOWN = "markdown" // chat prompts flattened under ## Role headings
+	FORMAT_JSON     = "json"     // array of {"role", "content"} messages
+)
+
+// Message is a single turn of a chat prompt
+type Message struct {
+	Role    string `yaml:"role" json:"role"`
+	Content string `yaml:"content" json:"content"`
+}
+
+// IsChatPrompt reports whether path names a chat prompt file such as review.prompt.yaml
+func IsChatPrompt(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), CHAT_EXTENSION)
+}
+
+// ParsePrompt splits a stored prompt into its frontmatter and the body that is
+// edited and rendered. The body of a chat prompt is its messages: block
+func ParsePrompt(info *PromptInfo) (*Frontmatter, string, error) {
+	if IsChatPrompt(info.Path) {
+		return ParseChatPrompt(info.Content)
+	}
+	return ParseFrontmatter(info.Content)
+}
+
+// ParseChatPrompt parses a chat prompt file. Every top-level key except
+// messages is read as frontmatter; the body is the messages: block exactly as written
+func ParseChatPrompt(content string) (*Frontmatter, string, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		return nil, "", fmt.Errorf("invalid chat prompt: %w", err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, "", fmt.Errorf("invalid chat prompt: expected a mapping with a messages: list")
+	}
+	root := document.Content[0]
+
+	frontmatter := &Frontmatter{}
+	if err := root.Decode(frontmatter); err != nil {
+		return nil, "", fmt.Errorf("invalid chat prompt: %w", err)
+	}
+	defaults, err := decodeDefaults(content)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid chat prompt: %w", err)
+	}
+	frontmatter.Defaults = defaults
+	if err := frontmatter.Variables.Check(); err != nil {
+		return nil, "", err
+	}
+
+	// Cut the messages: entry out of the file, up to the next top-level key
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	var body string
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "messages" {
+			continue
+		}
+		end := len(lines)
+		if i+2 < len(root.Content) {
+			end = root.Content[i+2].Line - 1
+		}
+		body = strings.Join(lines[root.Content[i].Line-1:end], "\n")
+	}
+
+	if _, err := ParseMessages(body); err != nil {
+		return nil, "", err
+	}
+	return frontmatter, body, nil
+}
+
+// ParseMessages parses and checks the messages: block of a chat prompt
+func ParseMessages(body string) ([]Message, error) {
+	var chat struct {
+		Messages []Message `yaml:"messages"`
+	}
+	if err := yaml.Unmarshal([]byte(body), &chat); err != nil {
+		return nil, fmt.Errorf("invalid chat prompt: %w", err)
+	}
+	if len(chat.Messages) == 0 {
+		return nil, fmt.Errorf("invalid chat prompt: no messages")
+	}
+
+	for i, message := range chat.Messages {
+		switch message.Role {
+		case ROLE_SYSTEM, ROLE_USER, ROLE_ASSISTANT:
+		default:
+			return nil, fmt.Errorf("invalid chat prompt: message %d: unknown role %q (use %s, %s or %s)", i+1, message.Role, ROLE_SYSTEM, ROLE_USER, ROLE_ASSISTANT)
+		}
+	}
+	return chat.Messages, nil
+}
+
+// SplitMessages returns the messages of a prompt body. A markdown prompt is a single user message
+func SplitMessages(path, body string) ([]Message, error) {
+	if IsChatPrompt(path) {
+		return ParseMessages(body)
+	}
+	return []Message{{Role: ROLE_USER, Content: body}}, nil
+}
+
+// FormatMessages writes messages in one of the FORMAT_* output formats
+func FormatMessages(messages []Message, format string) (string, error) {
+	switch format {
+	case FORMAT_MARKDOWN:
+		sections := make([]string, 0, len(messages))
+		for _, message := range messages {
+			title := message.Role
+			if title != "" {
+				title = strings.ToUpper(title[:1]) + title[1:]
+			}
+			sections = append(sections, "## "+title+"\n\n"+strings.TrimRight(message.Content, "\n"))
+		}
+		return strings.Join(sections, "\n\n") + "\n", nil
+	case FORMAT_JSON:
+		data, err := json.MarshalIndent(messages, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	}
+	return "", fmt.Errorf("unknown output format %q (available: %s, %s)", format, FORMAT_MARKDOWN, FORMAT_JSON)
+}
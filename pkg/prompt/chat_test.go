@@ -0,0 +1,125 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+const testChatPrompt = `description: Review code
+messages:
+  - role: system
+    content: You review ${LANGUAGE} code.
+  - role: user
+    content: |
+      Review this:
+      ${CODE}
+  - role: assistant
+    content: Sure.
+defaults:
+  LANGUAGE: Go
+`
+
+func TestParseChatPrompt(t *testing.T) {
+	frontmatter, body, err := ParseChatPrompt(testChatPrompt)
+	if err != nil {
+		t.Fatalf("ParseChatPrompt() error = %v", err)
+	}
+	if frontmatter.Description != "Review code" || frontmatter.Defaults["LANGUAGE"] != "Go" {
+		t.Errorf("ParseChatPrompt() frontmatter = %+v", frontmatter)
+	}
+	if !strings.HasPrefix(body, "messages:\n") || strings.Contains(body, "defaults:") {
+		t.Errorf("ParseChatPrompt() body = %q, want only the messages block", body)
+	}
+
+	messages, err := ParseMessages(body)
+	if err != nil {
+		t.Fatalf("ParseMessages() error = %v", err)
+	}
+	expected := []Message{
+		{Role: ROLE_SYSTEM, Content: "You review ${LANGUAGE} code."},
+		{Role: ROLE_USER, Content: "Review this:\n${CODE}\n"},
+		{Role: ROLE_ASSISTANT, Content: "Sure."},
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("ParseMessages() = %+v, want %+v", messages, expected)
+	}
+	for i := range expected {
+		if messages[i] != expected[i] {
+			t.Errorf("ParseMessages()[%d] = %+v, want %+v", i, messages[i], expected[i])
+		}
+	}
+}
+
+func TestParseChatPromptInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"not a mapping", "- role: user", "expected a mapping"},
+		{"no messages", "description: empty\n", "no messages"},
+		{"unknown role", "messages:\n  - role: robot\n    content: hi\n", `message 1: unknown role "robot"`},
+		{"invalid yaml", "messages: [unclosed", "invalid chat prompt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseChatPrompt(tt.content)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseChatPrompt() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatMessages(t *testing.T) {
+	messages := []Message{
+		{Role: ROLE_SYSTEM, Content: "Be brief."},
+		{Role: ROLE_USER, Content: "Hi\n"},
+	}
+
+	markdown, err := FormatMessages(messages, FORMAT_MARKDOWN)
+	if err != nil {
+		t.Fatalf("FormatMessages() error = %v", err)
+	}
+	if expected := "## System\n\nBe brief.\n\n## User\n\nHi\n"; markdown != expected {
+		t.Errorf("FormatMessages(markdown) = %q, want %q", markdown, expected)
+	}
+
+	json, err := FormatMessages(messages, FORMAT_JSON)
+	if err != nil {
+		t.Fatalf("FormatMessages() error = %v", err)
+	}
+	if expected := "[\n  {\n    \"role\": \"system\",\n    \"content\": \"Be brief.\"\n  },\n  {\n    \"role\": \"user\",\n    \"content\": \"Hi\\n\"\n  }\n]\n"; json != expected {
+		t.Errorf("FormatMessages(json) = %q, want %q", json, expected)
+	}
+
+	if _, err := FormatMessages(messages, "xml"); err == nil {
+		t.Error("FormatMessages() expected error for unknown format")
+	}
+}
+
+func TestDefaultManagerListChatPrompts(t *testing.T) {
+	fs := filesystem.NewFakeFilesystem()
+	fs.MapFS["prompts/review.prompt.yaml"] = &fstest.MapFile{Data: []byte(testChatPrompt), Mode: 0644}
+	fs.MapFS["prompts/notes.yaml"] = &fstest.MapFile{Data: []byte("not: a prompt"), Mode: 0644}
+
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{
+		{Type: "directory", Path: "prompts"},
+	}
+
+	prompts, err := NewDefaultManager(fs, resolver).List()
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(prompts) != 1 || prompts[0].Name != "review" {
+		t.Fatalf("List() = %+v, want only the review chat prompt", prompts)
+	}
+	if prompts[0].Metadata.Description != "Review code" {
+		t.Errorf("List() metadata = %+v", prompts[0].Metadata)
+	}
+}
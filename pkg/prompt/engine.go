@@ -0,0 +1,308 @@
+package prompt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Template engines a prompt can select with `engine:` in its frontmatter
+const (
+	ENGINE_NATIVE     = "native"
+	ENGINE_GOTEMPLATE = "gotemplate"
+	ENGINE_MUSTACHE   = "mustache"
+)
+
+// Engine renders prompt bodies written in a particular template language
+type Engine interface {
+	// Variables lists the values the template expects, in order of first use
+	Variables(content string) ([]Placeholder, error)
+	Render(content string, values map[string]any) (string, error)
+}
+
+// EngineRegistry maps engine names to engines
+type EngineRegistry struct {
+	engines map[string]Engine
+}
+
+// NewEngineRegistry creates an empty EngineRegistry
+func NewEngineRegistry() *EngineRegistry {
+	return &EngineRegistry{
+		engines: make(map[string]Engine),
+	}
+}
+
+// NewDefaultEngineRegistry creates an EngineRegistry with the native ${}
+// engine backed by parser, Go text/template and mustache
+func NewDefaultEngineRegistry(parser Parser) *EngineRegistry {
+	registry := NewEngineRegistry()
+	registry.Register(ENGINE_NATIVE, NewNativeEngine(parser))
+	registry.Register(ENGINE_GOTEMPLATE, NewGoTemplateEngine())
+	registry.Register(ENGINE_MUSTACHE, NewMustacheEngine())
+	return registry
+}
+
+// Register adds or replaces the engine available under name
+func (r *EngineRegistry) Register(name string, engine Engine) {
+	r.engines[name] = engine
+}
+
+// Lookup returns the engine registered as name; an empty name selects the native engine
+func (r *EngineRegistry) Lookup(name string) (Engine, error) {
+	if name == "" {
+		name = ENGINE_NATIVE
+	}
+	engine, ok := r.engines[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template engine %q (available: %s)", name, strings.Join(r.names(), ", "))
+	}
+	return engine, nil
+}
+
+// names returns the registered engine names in sorted order
+func (r *EngineRegistry) names() []string {
+	names := make([]string, 0, len(r.engines))
+	for name := range r.engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NativeEngine renders the ${VAR} placeholder syntax through a Parser
+type NativeEngine struct {
+	Parser Parser
+}
+
+// NewNativeEngine creates a new NativeEngine
+func NewNativeEngine(parser Parser) *NativeEngine {
+	return &NativeEngine{
+		Parser: parser,
+	}
+}
+
+// Variables implements Engine
+func (e *NativeEngine) Variables(content string) ([]Placeholder, error) {
+	return e.Parser.ParsePlaceholders(content)
+}
+
+// Render implements Engine
+func (e *NativeEngine) Render(content string, values map[string]any) (string, error) {
+	return e.Parser.SubstitutePlaceholders(content, values)
+}
+
+// GoTemplateEngine renders Go text/template prompts, e.g. {{if bool .SECURITY}}...{{end}}.
+// Lists and maps from the frontmatter can be ranged over directly; the lines and bool
+// functions also turn string values into lists and conditions
+type GoTemplateEngine struct{}
+
+// NewGoTemplateEngine creates a new GoTemplateEngine
+func NewGoTemplateEngine() *GoTemplateEngine {
+	return &GoTemplateEngine{}
+}
+
+// goTemplateFuncs are the functions available to gotemplate prompts
+var goTemplateFuncs = template.FuncMap{
+	"lines":  listItems,
+	"split":  strings.Split,
+	"join":   joinValue,
+	"format": FormatValue,
+	"trim":   strings.TrimSpace,
+	"upper":  strings.ToUpper,
+	"lower":  strings.ToLower,
+	"bool":   isTruthy,
+}
+
+// parse parses content as a Go template
+func (e *GoTemplateEngine) parse(content string) (*template.Template, error) {
+	tmpl, err := template.New("prompt").Funcs(goTemplateFuncs).Option("missingkey=zero").Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gotemplate: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Variables implements Engine, reporting the fields used on the top-level value such as .NAME or $.NAME
+func (e *GoTemplateEngine) Variables(content string) ([]Placeholder, error) {
+	tmpl, err := e.parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	collector := newVariableCollector()
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			collectTemplateFields(t.Tree.Root, true, collector.add)
+		}
+	}
+	return collector.placeholders, nil
+}
+
+// Render implements Engine
+func (e *GoTemplateEngine) Render(content string, values map[string]any) (string, error) {
+	tmpl, err := e.parse(content)
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, templateData(values)); err != nil {
+		return "", fmt.Errorf("failed to render gotemplate: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// collectTemplateFields walks a template tree and reports fields of the root
+// value. Inside range and with blocks dot is rebound, so only $.NAME counts there
+func collectTemplateFields(node parse.Node, rootDot bool, add func(string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectTemplateFields(child, rootDot, add)
+		}
+	case *parse.ActionNode:
+		collectTemplateFields(n.Pipe, rootDot, add)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectTemplateFields(cmd, rootDot, add)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectTemplateFields(arg, rootDot, add)
+		}
+	case *parse.ChainNode:
+		collectTemplateFields(n.Node, rootDot, add)
+	case *parse.FieldNode:
+		if rootDot {
+			addField(n.Ident, add)
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			addField(n.Ident[1:], add)
+		}
+	case *parse.IfNode:
+		collectTemplateFields(n.Pipe, rootDot, add)
+		collectTemplateFields(n.List, rootDot, add)
+		collectTemplateFields(n.ElseList, rootDot, add)
+	case *parse.RangeNode:
+		collectTemplateFields(n.Pipe, rootDot, add)
+		collectTemplateFields(n.List, false, add)
+		collectTemplateFields(n.ElseList, rootDot, add)
+	case *parse.WithNode:
+		collectTemplateFields(n.Pipe, rootDot, add)
+		collectTemplateFields(n.List, false, add)
+		collectTemplateFields(n.ElseList, rootDot, add)
+	case *parse.TemplateNode:
+		collectTemplateFields(n.Pipe, rootDot, add)
+	}
+}
+
+// addField reports the variable a field chain such as .NAME refers to, skipping
+// built-in variables such as .git.branch
+func addField(ident []string, add func(string)) {
+	if !IsBuiltin(strings.Join(ident, ".")) {
+		add(ident[0])
+	}
+}
+
+// templateData converts values for text/template, nesting dotted names so that
+// git.branch is available as .git.branch
+func templateData(values map[string]any) map[string]any {
+	data := make(map[string]any, len(values))
+	for name, value := range values {
+		parts := strings.Split(name, ".")
+		current := data
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := current[part].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				current[part] = next
+			}
+			current = next
+		}
+		if _, nested := current[parts[len(parts)-1]].(map[string]any); !nested {
+			current[parts[len(parts)-1]] = value
+		}
+	}
+	return data
+}
+
+// variableCollector builds a placeholder list from names in order of first use
+type variableCollector struct {
+	placeholders []Placeholder
+	seen         map[string]bool
+}
+
+// newVariableCollector creates an empty variableCollector
+func newVariableCollector() *variableCollector {
+	return &variableCollector{
+		seen: make(map[string]bool),
+	}
+}
+
+// add records name unless it was seen before
+func (c *variableCollector) add(name string) {
+	if c.seen[name] {
+		return
+	}
+	c.seen[name] = true
+	c.placeholders = append(c.placeholders, Placeholder{Name: name})
+}
+
+// splitLines splits a value into its non-blank lines, so multi-line values can be used as lists
+func splitLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimRight(line, "\r"))
+		}
+	}
+	return lines
+}
+
+// joinValue joins the items of a list value with sep, e.g. {{join .FILES ", "}}
+func joinValue(value any, sep string) string {
+	var items []string
+	for _, item := range listItems(value) {
+		items = append(items, FormatValue(item))
+	}
+	return strings.Join(items, sep)
+}
+
+// FakeEngine simulates engine behavior for testing
+type FakeEngine struct {
+	Placeholders []Placeholder
+	Rendered     string
+	ShouldFail   bool
+}
+
+// NewFakeEngine creates a new FakeEngine
+func NewFakeEngine() *FakeEngine {
+	return &FakeEngine{
+		Placeholders: make([]Placeholder, 0),
+	}
+}
+
+// Variables returns the predefined placeholders for testing
+func (f *FakeEngine) Variables(content string) ([]Placeholder, error) {
+	if f.ShouldFail {
+		return nil, fmt.Errorf("fake engine error")
+	}
+	return f.Placeholders, nil
+}
+
+// Render returns the predefined output for testing
+func (f *FakeEngine) Render(content string, values map[string]any) (string, error) {
+	if f.ShouldFail {
+		return "", fmt.Errorf("fake engine error")
+	}
+	return f.Rendered, nil
+}
@@ -0,0 +1,79 @@
+package prompt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEngineRegistry_Lookup(t *testing.T) {
+	registry := NewDefaultEngineRegistry(NewDefaultParser())
+
+	for _, name := range []string{"", ENGINE_NATIVE, ENGINE_GOTEMPLATE, ENGINE_MUSTACHE} {
+		if _, err := registry.Lookup(name); err != nil {
+			t.Errorf("Lookup(%q) error = %v", name, err)
+		}
+	}
+
+	_, err := registry.Lookup("jinja")
+	if err == nil || !strings.Contains(err.Error(), "gotemplate, mustache, native") {
+		t.Errorf("Lookup(jinja) error = %v, want unknown engine listing the available ones", err)
+	}
+
+	fake := NewFakeEngine()
+	registry.Register("fake", fake)
+	if engine, err := registry.Lookup("fake"); err != nil || engine != fake {
+		t.Errorf("Lookup(fake) = %v, %v, want registered engine", engine, err)
+	}
+}
+
+func TestGoTemplateEngine(t *testing.T) {
+	engine := NewGoTemplateEngine()
+	content := "Review {{.LANGUAGE}} code.\n" +
+		"{{if bool .SECURITY}}Check for injection.\n{{end}}" +
+		"{{range lines .FILES}}- {{.}} ({{$.LANGUAGE}})\n{{end}}" +
+		"{{with .NOTE}}Note: {{.}}{{end}}"
+
+	placeholders, err := engine.Variables(content)
+	if err != nil {
+		t.Fatalf("Variables() error = %v", err)
+	}
+	var names []string
+	for _, p := range placeholders {
+		names = append(names, p.Name)
+	}
+	if want := []string{"LANGUAGE", "SECURITY", "FILES", "NOTE"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Variables() = %v, want %v", names, want)
+	}
+
+	result, err := engine.Render(content, map[string]any{
+		"LANGUAGE": "Go",
+		"SECURITY": "false",
+		"FILES":    "main.go\nparser.go\n",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	expected := "Review Go code.\n- main.go (Go)\n- parser.go (Go)\n"
+	if result != expected {
+		t.Errorf("Render() = %q, want %q", result, expected)
+	}
+
+	if _, err := engine.Variables("{{if .X}}"); err == nil {
+		t.Error("Variables() expected error for unclosed if")
+	}
+}
+
+func TestNativeEngine(t *testing.T) {
+	engine := NewNativeEngine(NewDefaultParser())
+
+	placeholders, err := engine.Variables("Hello ${NAME:-World}")
+	if err != nil || len(placeholders) != 1 || placeholders[0].DefaultValue != "World" {
+		t.Fatalf("Variables() = %+v, %v", placeholders, err)
+	}
+
+	result, err := engine.Render("Hello ${NAME:-World}", map[string]any{})
+	if err != nil || result != "Hello World" {
+		t.Errorf("Render() = %q, %v, want %q", result, err, "Hello World")
+	}
+}
@@ -0,0 +1,493 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrRequiredPlaceholder is returned when a ${VAR:?message} placeholder has no value
+var ErrRequiredPlaceholder = errors.New("required placeholder not set")
+
+// ErrUnresolvedPlaceholder is returned when strict rendering finds placeholders with neither a value nor a default
+var ErrUnresolvedPlaceholder = errors.New("unresolved placeholders")
+
+// ErrPlaceholderCycle is returned when placeholder defaults reference each other
+var ErrPlaceholderCycle = errors.New("placeholder cycle")
+
+// Expansion operators understood inside ${...}
+const (
+	OP_NONE           = ""
+	OP_DEFAULT        = ":-"     // ${VAR:-word}
+	OP_DEFAULT_UNSET  = "-"      // ${VAR-word}
+	OP_ASSIGN         = ":="     // ${VAR:=word}
+	OP_ASSIGN_UNSET   = "="      // ${VAR=word}
+	OP_REQUIRED       = ":?"     // ${VAR:?message}
+	OP_REQUIRED_UNSET = "?"      // ${VAR?message}
+	OP_ALTERNATE      = ":+"     // ${VAR:+word}
+	OP_ALTERNATE_SET  = "+"      // ${VAR+word}
+	OP_LENGTH         = "length" // ${#VAR}
+	OP_TRIM_PREFIX    = "#"      // ${VAR#pattern}
+	OP_TRIM_PREFIX_L  = "##"     // ${VAR##pattern}
+	OP_TRIM_SUFFIX    = "%"      // ${VAR%pattern}
+	OP_TRIM_SUFFIX_L  = "%%"     // ${VAR%%pattern}
+	OP_REPLACE        = "/"      // ${VAR/pattern/replacement}
+	OP_REPLACE_ALL    = "//"     // ${VAR//pattern/replacement}
+	OP_REPLACE_PREFIX = "/#"     // ${VAR/#pattern/replacement}
+	OP_REPLACE_SUFFIX = "/%"     // ${VAR/%pattern/replacement}
+	OP_UPPER_FIRST    = "^"      // ${VAR^}
+	OP_UPPER_ALL      = "^^"     // ${VAR^^}
+	OP_LOWER_FIRST    = ","      // ${VAR,}
+	OP_LOWER_ALL      = ",,"     // ${VAR,,}
+	OP_SUBSTRING      = ":"      // ${VAR:offset} and ${VAR:offset:length}
+)
+
+// expansion is a parsed ${...} expression
+type expansion struct {
+	Name    string
+	Op      string
+	Word    string // default, alternate, message, pattern or offset
+	Replace string // replacement for the pattern substitution operators
+	Length  string // length for the substring operator
+	HasLen  bool   // whether the substring operator carried a length
+
+	wordOffset    int       // offset of Word within the expression body
+	replaceOffset int       // offset of Replace within the expression body
+	word          *Template // Word parsed as a template, set by the template parser
+	replace       *Template // Replace parsed as a template, set by the template parser
+	filters       []filterCall
+}
+
+// providesDefault reports whether the expansion supplies a default value for its variable
+func (e expansion) providesDefault() bool {
+	switch e.Op {
+	case OP_DEFAULT, OP_DEFAULT_UNSET, OP_ASSIGN, OP_ASSIGN_UNSET:
+		return true
+	}
+	return false
+}
+
+// hasWord reports whether Word is text that may contain nested placeholders
+func (e expansion) hasWord() bool {
+	switch e.Op {
+	case OP_NONE, OP_LENGTH, OP_SUBSTRING, OP_UPPER_FIRST, OP_UPPER_ALL, OP_LOWER_FIRST, OP_LOWER_ALL:
+		return false
+	}
+	return true
+}
+
+// hasReplacement reports whether the expansion is a pattern substitution
+func (e expansion) hasReplacement() bool {
+	switch e.Op {
+	case OP_REPLACE, OP_REPLACE_ALL, OP_REPLACE_PREFIX, OP_REPLACE_SUFFIX:
+		return true
+	}
+	return false
+}
+
+// required reports whether the expansion fails when its variable has no value
+func (e expansion) required() bool {
+	return e.Op == OP_REQUIRED || e.Op == OP_REQUIRED_UNSET
+}
+
+// parseExpansion parses the text between ${ and }
+func parseExpansion(body string) (expansion, error) {
+	if strings.HasPrefix(body, "#") && len(body) > 1 {
+		name := body[1:]
+		if isValidName(name) {
+			return expansion{Name: name, Op: OP_LENGTH}, nil
+		}
+	}
+
+	nameEnd := scanName(body)
+	if nameEnd == 0 {
+		return expansion{}, fmt.Errorf("invalid placeholder name in ${%s}", body)
+	}
+
+	exp := expansion{Name: body[:nameEnd]}
+	rest := body[nameEnd:]
+	if rest == "" {
+		return exp, nil
+	}
+
+	for _, op := range []string{OP_DEFAULT, OP_ASSIGN, OP_REQUIRED, OP_ALTERNATE} {
+		if strings.HasPrefix(rest, op) {
+			exp.Op = op
+			exp.Word, exp.wordOffset = rest[len(op):], nameEnd+len(op)
+			return exp, nil
+		}
+	}
+
+	switch rest[0] {
+	case '-', '=', '?', '+':
+		exp.Op = rest[:1]
+		exp.Word, exp.wordOffset = rest[1:], nameEnd+1
+		return exp, nil
+	case '#', '%':
+		exp.Op = rest[:1]
+		if len(rest) > 1 && rest[1] == rest[0] {
+			exp.Op = rest[:2]
+		}
+		exp.Word, exp.wordOffset = rest[len(exp.Op):], nameEnd+len(exp.Op)
+		return exp, nil
+	case '/':
+		exp.Op = OP_REPLACE
+		if len(rest) > 1 && strings.ContainsRune("/#%", rune(rest[1])) {
+			exp.Op = rest[:2]
+		}
+		exp.Word, exp.Replace = splitPattern(rest[len(exp.Op):])
+		exp.wordOffset = nameEnd + len(exp.Op)
+		exp.replaceOffset = exp.wordOffset + len(exp.Word) + 1
+		return exp, nil
+	case '^', ',':
+		exp.Op = rest[:1]
+		if len(rest) > 1 && rest[1] == rest[0] {
+			exp.Op = rest[:2]
+		}
+		if len(rest) != len(exp.Op) {
+			return expansion{}, fmt.Errorf("unsupported case modification in ${%s}", body)
+		}
+		return exp, nil
+	case ':':
+		exp.Op = OP_SUBSTRING
+		offset, length, hasLen := strings.Cut(rest[1:], ":")
+		if _, err := strconv.Atoi(strings.TrimSpace(offset)); err != nil {
+			return expansion{}, fmt.Errorf("invalid substring offset in ${%s}", body)
+		}
+		if hasLen {
+			if _, err := strconv.Atoi(strings.TrimSpace(length)); err != nil {
+				return expansion{}, fmt.Errorf("invalid substring length in ${%s}", body)
+			}
+		}
+		exp.Word, exp.Length, exp.HasLen = offset, length, hasLen
+		return exp, nil
+	}
+
+	return expansion{}, fmt.Errorf("invalid placeholder name in ${%s}", body)
+}
+
+// scanName returns the length of the variable name at the start of s.
+// Only names of built-in variables may be dotted, as in git.branch, so
+// ${user.name} in a JavaScript snippet is not a placeholder
+func scanName(s string) int {
+	for i, r := range s {
+		if r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		if r == '.' && i > 0 && IsBuiltin(s[:i+1]) && scanName(s[i+1:]) > 0 {
+			continue
+		}
+		return i
+	}
+	return len(s)
+}
+
+// isValidName checks if s is a complete variable name
+func isValidName(s string) bool {
+	return s != "" && scanName(s) == len(s)
+}
+
+// splitPattern splits "pattern/replacement" at the first unescaped slash
+// outside of nested placeholders
+func splitPattern(s string) (string, string) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' || strings.HasPrefix(s[i:], "$$"):
+			i++
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}' && depth > 0:
+			depth--
+		case s[i] == '/' && depth == 0:
+			return s[:i], s[i+1:]
+		}
+	}
+	return s, ""
+}
+
+// evaluate computes the value of the expansion and runs it through its filters
+func (e expansion) evaluate(scope *renderScope) (string, error) {
+	value, err := e.expand(scope)
+	if err != nil || len(e.filters) == 0 {
+		return value, err
+	}
+	value, err = scope.filters.apply(value, e.filters)
+	if err != nil {
+		return "", fmt.Errorf("placeholder %s: %w", e.Name, err)
+	}
+	return value, nil
+}
+
+// expand computes the value of the expansion, assigning into the scope for ${VAR:=word}
+func (e expansion) expand(scope *renderScope) (string, error) {
+	switch e.Op {
+	case OP_DEFAULT, OP_DEFAULT_UNSET:
+		// Like in bash, ${VAR:-word} also replaces an empty value; ${VAR-word} keeps it
+		if value, set := scope.values[e.Name]; set && (value != "" || e.Op == OP_DEFAULT_UNSET) {
+			return value, nil
+		}
+		return scope.resolveDefault(e.Name, e.word, e.Word)
+	case OP_ASSIGN, OP_ASSIGN_UNSET:
+		if value, set := scope.values[e.Name]; set && (value != "" || e.Op == OP_ASSIGN_UNSET) {
+			return value, nil
+		}
+		word, err := scope.resolveDefault(e.Name, e.word, e.Word)
+		if err != nil {
+			return "", err
+		}
+		scope.values[e.Name] = word
+		return word, nil
+	}
+
+	value, set, err := scope.lookup(e.Name)
+	if err != nil {
+		return "", err
+	}
+
+	switch e.Op {
+	case OP_NONE:
+		return value, nil
+	case OP_LENGTH:
+		return strconv.Itoa(utf8.RuneCountInString(value)), nil
+	case OP_REQUIRED, OP_REQUIRED_UNSET:
+		if set && (value != "" || e.Op == OP_REQUIRED_UNSET) {
+			return value, nil
+		}
+		message, err := scope.renderWord(e.word, e.Word)
+		if err != nil {
+			return "", err
+		}
+		if message == "" {
+			message = "parameter null or not set"
+		}
+		return "", fmt.Errorf("%w: %s: %s", ErrRequiredPlaceholder, e.Name, message)
+	case OP_ALTERNATE, OP_ALTERNATE_SET:
+		if set && (value != "" || e.Op == OP_ALTERNATE_SET) {
+			return scope.renderWord(e.word, e.Word)
+		}
+		return "", nil
+	case OP_TRIM_PREFIX, OP_TRIM_PREFIX_L, OP_TRIM_SUFFIX, OP_TRIM_SUFFIX_L:
+		pattern, err := scope.render(e.word, e.Word)
+		if err != nil {
+			return "", err
+		}
+		return trimPattern(value, pattern, e.Op), nil
+	case OP_REPLACE, OP_REPLACE_ALL, OP_REPLACE_PREFIX, OP_REPLACE_SUFFIX:
+		pattern, err := scope.render(e.word, e.Word)
+		if err != nil {
+			return "", err
+		}
+		replacement, err := scope.render(e.replace, e.Replace)
+		if err != nil {
+			return "", err
+		}
+		return replacePattern(value, pattern, unescape(replacement), e.Op), nil
+	case OP_UPPER_FIRST, OP_UPPER_ALL, OP_LOWER_FIRST, OP_LOWER_ALL:
+		return changeCase(value, e.Op), nil
+	case OP_SUBSTRING:
+		return substring(value, e.Word, e.Length, e.HasLen), nil
+	}
+
+	return value, nil
+}
+
+// trimPattern implements the #, ##, % and %% operators, cutting value only between runes
+func trimPattern(value, pattern, op string) string {
+	re := globToRegexp(pattern)
+	longest := len(op) == 2
+	prefix := op[0] == '#'
+
+	best := -1
+	for i := 0; i <= len(value); i++ {
+		if prefix && !runeBoundary(value, i) || !prefix && !runeBoundary(value, len(value)-i) {
+			continue
+		}
+		var candidate string
+		if prefix {
+			candidate = value[:i]
+		} else {
+			candidate = value[len(value)-i:]
+		}
+		if re.MatchString(candidate) {
+			best = i
+			if !longest {
+				break
+			}
+		}
+	}
+
+	if best < 0 {
+		return value
+	}
+	if prefix {
+		return value[best:]
+	}
+	return value[:len(value)-best]
+}
+
+// replacePattern implements the /, //, /# and /% operators, matching whole runes
+func replacePattern(value, pattern, replacement, op string) string {
+	if pattern == "" {
+		return value
+	}
+	re := globToRegexp(pattern)
+
+	var result strings.Builder
+	i := 0
+	for i <= len(value) {
+		// Find the longest match starting at i
+		end := -1
+		for j := len(value); j >= i; j-- {
+			if op == OP_REPLACE_SUFFIX && j != len(value) {
+				break
+			}
+			if !runeBoundary(value, j) {
+				continue
+			}
+			if re.MatchString(value[i:j]) {
+				end = j
+				break
+			}
+		}
+
+		if end >= 0 && end > i {
+			result.WriteString(replacement)
+			i = end
+			if op != OP_REPLACE_ALL {
+				result.WriteString(value[i:])
+				return result.String()
+			}
+			continue
+		}
+
+		if op == OP_REPLACE_PREFIX {
+			return value
+		}
+		if i == len(value) {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(value[i:])
+		result.WriteString(value[i : i+size])
+		i += size
+	}
+
+	return result.String()
+}
+
+// runeBoundary reports whether i is at the start or end of a rune in s
+func runeBoundary(s string, i int) bool {
+	return i == len(s) || utf8.RuneStart(s[i])
+}
+
+// changeCase implements the ^, ^^, , and ,, operators
+func changeCase(value, op string) string {
+	switch op {
+	case OP_UPPER_ALL:
+		return strings.ToUpper(value)
+	case OP_LOWER_ALL:
+		return strings.ToLower(value)
+	}
+
+	r, size := utf8.DecodeRuneInString(value)
+	if size == 0 {
+		return value
+	}
+	if op == OP_UPPER_FIRST {
+		return string(unicode.ToUpper(r)) + value[size:]
+	}
+	return string(unicode.ToLower(r)) + value[size:]
+}
+
+// substring implements ${VAR:offset} and ${VAR:offset:length}, counting runes
+func substring(value, offsetStr, lengthStr string, hasLen bool) string {
+	runes := []rune(value)
+	offset, _ := strconv.Atoi(strings.TrimSpace(offsetStr))
+	if offset < 0 {
+		offset += len(runes)
+	}
+	if offset < 0 || offset > len(runes) {
+		return ""
+	}
+
+	end := len(runes)
+	if hasLen {
+		length, _ := strconv.Atoi(strings.TrimSpace(lengthStr))
+		if length < 0 {
+			end += length
+		} else if offset+length < end {
+			end = offset + length
+		}
+	}
+	if end < offset {
+		return ""
+	}
+
+	return string(runes[offset:end])
+}
+
+// globToRegexp converts a shell glob pattern into an anchored regular expression
+func globToRegexp(pattern string) *regexp.Regexp {
+	var buf strings.Builder
+	buf.WriteString(`^(?s:`)
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			buf.WriteString(`.*`)
+		case '?':
+			buf.WriteString(`.`)
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				buf.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				_, size := utf8.DecodeRuneInString(pattern[i+1:])
+				buf.WriteString(regexp.QuoteMeta(pattern[i+1 : i+1+size]))
+				i += size
+			} else {
+				buf.WriteString(`\\`)
+			}
+		default:
+			_, size := utf8.DecodeRuneInString(pattern[i:])
+			buf.WriteString(regexp.QuoteMeta(pattern[i : i+size]))
+			i += size - 1
+		}
+	}
+	buf.WriteString(`)$`)
+
+	re, err := regexp.Compile(buf.String())
+	if err != nil {
+		return regexp.MustCompile(`^` + regexp.QuoteMeta(pattern) + `$`)
+	}
+	return re
+}
+
+// unescape removes backslash escapes from a replacement string
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
@@ -0,0 +1,253 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ErrExtendsCycle is returned when prompts extend each other
+var ErrExtendsCycle = errors.New("extends cycle")
+
+var (
+	headingPattern    = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+	blockStartPattern = regexp.MustCompile(`^\s*<!--\s*block:\s*(.*?)\s*-->\s*$`)
+	blockEndPattern   = regexp.MustCompile(`^\s*<!--\s*endblock(:.*?)?\s*-->\s*$`)
+	fencePattern      = regexp.MustCompile("^ {0,3}(```|~~~)")
+)
+
+// ExtendsResolver resolves the extends: field of stored prompts by looking up
+// the parent prompt through a Manager and merging the child's blocks into it
+type ExtendsResolver struct {
+	Manager Manager
+}
+
+// NewExtendsResolver creates a new ExtendsResolver
+func NewExtendsResolver(manager Manager) *ExtendsResolver {
+	return &ExtendsResolver{
+		Manager: manager,
+	}
+}
+
+// Parse splits info into its frontmatter and body like ParsePrompt. If the
+// prompt extends another one, the body is the parent's body with the blocks
+// overridden by the child, and the frontmatter inherits the parent's engine,
+// variables and defaults
+func (r *ExtendsResolver) Parse(info *PromptInfo) (*Frontmatter, string, error) {
+	return r.parse(info, nil)
+}
+
+// parse resolves info; chain holds the prompts extending it, outermost first
+func (r *ExtendsResolver) parse(info *PromptInfo, chain []*PromptInfo) (*Frontmatter, string, error) {
+	frontmatter, body, err := ParsePrompt(info)
+	if err != nil || frontmatter.Extends == "" {
+		return frontmatter, body, err
+	}
+
+	chain = append(chain, info)
+	if IsChatPrompt(info.Path) {
+		return nil, "", fmt.Errorf("%s: chat prompts cannot extend other prompts", info.Name)
+	}
+
+	// A prompt extending its own name extends the prompt it shadows
+	var parent *PromptInfo
+	if frontmatter.Extends == info.Name {
+		parent, err = r.Manager.GetBelow(info.Name, info.Source)
+	} else {
+		parent, err = r.Manager.Get(frontmatter.Extends)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%s extends '%s': %w", info.Name, frontmatter.Extends, err)
+	}
+
+	for _, seen := range chain {
+		if seen.Path == parent.Path {
+			names := make([]string, 0, len(chain)+1)
+			for _, link := range chain {
+				names = append(names, link.Name)
+			}
+			return nil, "", fmt.Errorf("%w: %s", ErrExtendsCycle, strings.Join(append(names, parent.Name), " -> "))
+		}
+	}
+	if IsChatPrompt(parent.Path) {
+		return nil, "", fmt.Errorf("%s extends '%s': chat prompts cannot be extended", info.Name, parent.Name)
+	}
+
+	parentFrontmatter, parentBody, err := r.parse(parent, chain)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return inheritFrontmatter(frontmatter, parentFrontmatter), MergeBlocks(parentBody, body), nil
+}
+
+// inheritFrontmatter fills the engine, variables and defaults the child does not set from its parent
+func inheritFrontmatter(child, parent *Frontmatter) *Frontmatter {
+	merged := *child
+	if merged.Engine == "" {
+		merged.Engine = parent.Engine
+	}
+
+	merged.Variables = make(Schema)
+	for name, spec := range parent.Variables {
+		merged.Variables[name] = spec
+	}
+	for name, spec := range child.Variables {
+		merged.Variables[name] = spec
+	}
+
+	merged.Defaults = make(map[string]any)
+	for name, value := range parent.Defaults {
+		merged.Defaults[name] = value
+	}
+	for name, value := range child.Defaults {
+		merged.Defaults[name] = value
+	}
+	return &merged
+}
+
+// Block is a named section of a prompt body that a child prompt can override:
+// a markdown heading up to the next heading of the same or a higher level, or
+// the text between <!-- block:name --> and <!-- endblock --> including the markers
+type Block struct {
+	Name  string
+	Start int // byte offset of the first line
+	End   int // byte offset after the last line
+}
+
+// MergeBlocks replaces each block of parent with the block of the same name in
+// child. Child blocks the parent does not have, and any other non-blank text
+// of the child, are appended in order
+func MergeBlocks(parent, child string) string {
+	parentBlocks := FindBlocks(parent)
+	replacements := make(map[int]string) // index into parentBlocks -> child text
+	var appended []string
+
+	offset := 0
+	for _, block := range FindBlocks(child) {
+		if offset > block.Start {
+			continue // nested inside a block already handled
+		}
+		if text := child[offset:block.Start]; strings.TrimSpace(text) != "" {
+			appended = append(appended, text)
+		}
+		offset = block.End
+
+		text := child[block.Start:block.End]
+		if i := findBlock(parentBlocks, block.Name); i >= 0 {
+			replacements[i] = text
+		} else {
+			appended = append(appended, text)
+		}
+	}
+	if text := child[offset:]; strings.TrimSpace(text) != "" {
+		appended = append(appended, text)
+	}
+
+	var result strings.Builder
+	offset = 0
+	for i, block := range parentBlocks {
+		text, ok := replacements[i]
+		if !ok || offset > block.Start {
+			continue
+		}
+		result.WriteString(parent[offset:block.Start])
+		result.WriteString(withNewline(text))
+		offset = block.End
+	}
+	result.WriteString(parent[offset:])
+
+	for _, text := range appended {
+		if result.Len() > 0 && !strings.HasSuffix(result.String(), "\n") {
+			result.WriteString("\n")
+		}
+		result.WriteString(text)
+	}
+	return result.String()
+}
+
+// findBlock returns the index of the first block called name, or -1
+func findBlock(blocks []Block, name string) int {
+	for i, block := range blocks {
+		if block.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// withNewline ends text with a newline so the parent text after it starts on its own line
+func withNewline(text string) string {
+	if text == "" || strings.HasSuffix(text, "\n") {
+		return text
+	}
+	return text + "\n"
+}
+
+// FindBlocks returns the blocks of body in the order they start, nested blocks
+// after the block containing them. Headings inside fenced code are ignored.
+// Heading blocks are named by their slugified text, e.g. "Go Checklist" is go-checklist
+func FindBlocks(body string) []Block {
+	type open struct {
+		index int // into blocks
+		level int // heading level, or 0 for a block marker
+	}
+	var blocks []Block
+	var stack []open
+	closeUntil := func(keep func(open) bool, at int) {
+		for len(stack) > 0 && !keep(stack[len(stack)-1]) {
+			blocks[stack[len(stack)-1].index].End = at
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	fence := ""
+	for offset := 0; offset < len(body); {
+		lineEnd := len(body)
+		if i := strings.IndexByte(body[offset:], '\n'); i >= 0 {
+			lineEnd = offset + i + 1
+		}
+		line := strings.TrimRight(body[offset:lineEnd], "\r\n")
+
+		if match := fencePattern.FindStringSubmatch(line); match != nil {
+			if fence == "" {
+				fence = match[1]
+			} else if fence == match[1] {
+				fence = ""
+			}
+		} else if fence != "" {
+			// Inside fenced code
+		} else if match := headingPattern.FindStringSubmatch(line); match != nil {
+			level := len(match[1])
+			// A heading ends the open headings of the same or a deeper level, but not enclosing markers
+			closeUntil(func(o open) bool { return o.level == 0 || o.level < level }, offset)
+			stack = append(stack, open{index: len(blocks), level: level})
+			blocks = append(blocks, Block{Name: slugify(match[2]), Start: offset})
+		} else if match := blockStartPattern.FindStringSubmatch(line); match != nil {
+			stack = append(stack, open{index: len(blocks)})
+			blocks = append(blocks, Block{Name: slugify(match[1]), Start: offset})
+		} else if blockEndPattern.MatchString(line) {
+			// Close the headings opened inside the marker, then the marker itself
+			closeUntil(func(o open) bool { return o.level == 0 }, offset)
+			if len(stack) > 0 {
+				blocks[stack[len(stack)-1].index].End = lineEnd
+				stack = stack[:len(stack)-1]
+			}
+		}
+
+		offset = lineEnd
+	}
+	closeUntil(func(open) bool { return false }, len(body))
+
+	return blocks
+}
+
+// slugify lowercases text and joins its runs of letters and digits with '-'
+func slugify(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
@@ -0,0 +1,125 @@
+package prompt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+func TestFindBlocks(t *testing.T) {
+	body := "Intro\n## Checklist\n- a\n### Go Rules\n- b\n```\n# not a heading\n```\n## Output\n<!-- block:format -->\nJSON\n<!-- endblock -->\nDone"
+
+	var got []string
+	for _, block := range FindBlocks(body) {
+		got = append(got, block.Name+"="+body[block.Start:block.End])
+	}
+
+	expected := []string{
+		"checklist=## Checklist\n- a\n### Go Rules\n- b\n```\n# not a heading\n```\n",
+		"go-rules=### Go Rules\n- b\n```\n# not a heading\n```\n",
+		"output=## Output\n<!-- block:format -->\nJSON\n<!-- endblock -->\nDone",
+		"format=<!-- block:format -->\nJSON\n<!-- endblock -->\n",
+	}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("FindBlocks() = %q, want %q", got, expected)
+	}
+}
+
+func TestMergeBlocks(t *testing.T) {
+	parent := "# Review\nBe thorough.\n\n## Checklist\n- generic\n\n## Output\n<!-- block:format -->\nMarkdown\n<!-- endblock -->\n"
+
+	tests := []struct {
+		name  string
+		child string
+		want  string
+	}{
+		{
+			name:  "override heading block",
+			child: "## Checklist\n- Go specific\n",
+			want:  "# Review\nBe thorough.\n\n## Checklist\n- Go specific\n## Output\n<!-- block:format -->\nMarkdown\n<!-- endblock -->\n",
+		},
+		{
+			name:  "override nested marker block",
+			child: "<!-- block:format -->\nJSON\n<!-- endblock -->",
+			want:  "# Review\nBe thorough.\n\n## Checklist\n- generic\n\n## Output\n<!-- block:format -->\nJSON\n<!-- endblock -->\n",
+		},
+		{
+			name:  "new blocks and text are appended",
+			child: "\nAlso check tests.\n## Extra\nMore\n",
+			want:  parent + "\nAlso check tests.\n## Extra\nMore\n",
+		},
+		{
+			name:  "empty child keeps the parent",
+			child: "\n",
+			want:  parent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeBlocks(parent, tt.child); got != tt.want {
+				t.Errorf("MergeBlocks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtendsResolverParse(t *testing.T) {
+	fs := filesystem.NewFakeFilesystem()
+	fs.MapFS["project/prompts/code-review.md"] = &fstest.MapFile{
+		Data: []byte("---\nextends: code-review\nvariables:\n  LANGUAGE:\n    required: true\n---\n## Checklist\n- ${LANGUAGE} idioms\n"),
+	}
+	fs.MapFS["project/prompts/go-review.md"] = &fstest.MapFile{
+		Data: []byte("---\nextends: code-review\ndefaults:\n  LANGUAGE: Go\n---\n"),
+	}
+	fs.MapFS["user/prompts/code-review.md"] = &fstest.MapFile{
+		Data: []byte("---\nengine: native\ndefaults:\n  LANGUAGE: any\n---\nReview ${LANGUAGE}.\n## Checklist\n- generic\n"),
+	}
+	fs.MapFS["user/prompts/a.md"] = &fstest.MapFile{Data: []byte("---\nextends: b\n---\n")}
+	fs.MapFS["user/prompts/b.md"] = &fstest.MapFile{Data: []byte("---\nextends: a\n---\n")}
+
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{
+		{Type: "project", Path: "project/prompts"},
+		{Type: "user", Path: "user/prompts"},
+	}
+	manager := NewDefaultManager(fs, resolver)
+	extends := NewExtendsResolver(manager)
+
+	// A project prompt extending its own name extends the user prompt it shadows
+	info, _ := manager.Get("code-review")
+	frontmatter, body, err := extends.Parse(info)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if body != "Review ${LANGUAGE}.\n## Checklist\n- ${LANGUAGE} idioms\n" {
+		t.Errorf("Parse() body = %q", body)
+	}
+	if frontmatter.Engine != ENGINE_NATIVE || !frontmatter.Variables["LANGUAGE"].Required || frontmatter.Defaults["LANGUAGE"] != "any" {
+		t.Errorf("Parse() frontmatter = %+v", frontmatter)
+	}
+
+	// Extending another name goes through the whole hierarchy, recursively
+	info, _ = manager.Get("go-review")
+	frontmatter, body, err = extends.Parse(info)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if body != "Review ${LANGUAGE}.\n## Checklist\n- ${LANGUAGE} idioms\n" || frontmatter.Defaults["LANGUAGE"] != "Go" {
+		t.Errorf("Parse() = %+v, %q", frontmatter, body)
+	}
+
+	info, _ = manager.Get("a")
+	if _, _, err := extends.Parse(info); !errors.Is(err, ErrExtendsCycle) {
+		t.Errorf("Parse() error = %v, want ErrExtendsCycle", err)
+	}
+
+	fs.MapFS["user/prompts/orphan.md"] = &fstest.MapFile{Data: []byte("---\nextends: orphan\n---\n")}
+	info, _ = manager.Get("orphan")
+	if _, _, err := extends.Parse(info); !errors.Is(err, ErrPromptNotFound) {
+		t.Errorf("Parse() error = %v, want ErrPromptNotFound", err)
+	}
+}
@@ -0,0 +1,209 @@
+package prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownFilter is returned when a placeholder uses a filter that is not registered
+var ErrUnknownFilter = errors.New("unknown filter")
+
+// Filter transforms a placeholder value, e.g. fence in ${CODE|fence:go}.
+// arg is the text after the first colon, or "" if there is none
+type Filter interface {
+	Apply(value, arg string) (string, error)
+}
+
+// FilterFunc adapts a function to the Filter interface
+type FilterFunc func(value, arg string) (string, error)
+
+// Apply implements Filter
+func (f FilterFunc) Apply(value, arg string) (string, error) {
+	return f(value, arg)
+}
+
+// filterCall is a single |name:arg step of a placeholder pipeline
+type filterCall struct {
+	Name string
+	Arg  string
+}
+
+// FilterRegistry maps filter names to filters
+type FilterRegistry struct {
+	filters map[string]Filter
+}
+
+// NewFilterRegistry creates an empty FilterRegistry
+func NewFilterRegistry() *FilterRegistry {
+	return &FilterRegistry{
+		filters: make(map[string]Filter),
+	}
+}
+
+// NewDefaultFilterRegistry creates a FilterRegistry with the built-in filters
+func NewDefaultFilterRegistry() *FilterRegistry {
+	registry := NewFilterRegistry()
+	registry.Register("trim", textFilter(strings.TrimSpace))
+	registry.Register("upper", textFilter(strings.ToUpper))
+	registry.Register("lower", textFilter(strings.ToLower))
+	registry.Register("oneline", textFilter(func(value string) string { return strings.Join(strings.Fields(value), " ") }))
+	registry.Register("basename", textFilter(filepath.Base))
+	registry.Register("dirname", textFilter(filepath.Dir))
+	registry.Register("bullets", textFilter(func(value string) string { return prefixLines(value, func(int) string { return "- " }) }))
+	registry.Register("numbered", textFilter(func(value string) string {
+		return prefixLines(value, func(n int) string { return strconv.Itoa(n) + ". " })
+	}))
+	registry.Register("quote", textFilter(func(value string) string { return prefixLines(value, func(int) string { return "> " }) }))
+	registry.Register("join", FilterFunc(joinFilter))
+	registry.Register("indent", FilterFunc(indentFilter))
+	registry.Register("fence", FilterFunc(fenceFilter))
+	registry.Register("json", textFilter(func(value string) string {
+		data, _ := json.Marshal(value)
+		return string(data)
+	}))
+	return registry
+}
+
+// Register adds or replaces the filter available as |name
+func (r *FilterRegistry) Register(name string, filter Filter) {
+	r.filters[name] = filter
+}
+
+// Lookup returns the filter registered as name
+func (r *FilterRegistry) Lookup(name string) (Filter, bool) {
+	if r == nil {
+		return nil, false
+	}
+	filter, ok := r.filters[name]
+	return filter, ok
+}
+
+// apply runs value through a pipeline of filters, left to right
+func (r *FilterRegistry) apply(value string, calls []filterCall) (string, error) {
+	for _, call := range calls {
+		filter, ok := r.Lookup(call.Name)
+		if !ok {
+			return "", fmt.Errorf("%w %q", ErrUnknownFilter, call.Name)
+		}
+		var err error
+		value, err = filter.Apply(value, call.Arg)
+		if err != nil {
+			return "", fmt.Errorf("filter %s: %w", call.Name, err)
+		}
+	}
+	return value, nil
+}
+
+// splitFilters splits "NAME:-word|trim|fence:go" into the expression and its
+// filters. A pipe only starts the filters when every segment after it names a
+// filter of registry, so ${FORMAT:-json|yaml} keeps json|yaml as its default.
+// Pipes inside nested placeholders or escaped as \| never split. unknown is
+// the name of an unregistered filter the expression ends with, if any
+func splitFilters(body string, registry *FilterRegistry) (expression string, calls []filterCall, unknown string) {
+	pipes := []int{-1}
+	depth := 0
+	for i := 0; i < len(body); i++ {
+		switch {
+		case body[i] == '\\' || strings.HasPrefix(body[i:], "$$"):
+			i++
+		case strings.HasPrefix(body[i:], "${"):
+			depth++
+			i++
+		case body[i] == '}' && depth > 0:
+			depth--
+		case body[i] == '|' && depth == 0:
+			pipes = append(pipes, i)
+		}
+	}
+	pipes = append(pipes, len(body))
+
+	// Take filters from the end for as long as they are registered
+	split := len(pipes) - 1
+	for ; split > 1; split-- {
+		name, arg, _ := strings.Cut(body[pipes[split-1]+1:pipes[split]], ":")
+		name = strings.TrimSpace(name)
+		if _, ok := registry.Lookup(name); !ok {
+			if name != "" && isValidFilterName(name) {
+				unknown = name
+			}
+			break
+		}
+		calls = append([]filterCall{{Name: name, Arg: unescapePipes(arg)}}, calls...)
+	}
+	return body[:pipes[split]], calls, unknown
+}
+
+// isValidFilterName checks that name consists of letters, digits, '_' and '-'
+func isValidFilterName(name string) bool {
+	for _, r := range name {
+		if r != '-' && scanName(string(r)) == 0 && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// unescapePipes turns \| into | in text taken from inside a placeholder
+func unescapePipes(s string) string {
+	return strings.ReplaceAll(s, `\|`, "|")
+}
+
+// textFilter adapts a string function without arguments to the Filter interface
+func textFilter(fn func(string) string) Filter {
+	return FilterFunc(func(value, arg string) (string, error) {
+		if arg != "" {
+			return "", fmt.Errorf("takes no argument")
+		}
+		return fn(value), nil
+	})
+}
+
+// prefixLines prefixes each non-blank line of value; prefix receives the 1-based line count
+func prefixLines(value string, prefix func(n int) string) string {
+	lines := strings.Split(strings.TrimRight(value, "\n"), "\n")
+	n := 0
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n++
+		lines[i] = prefix(n) + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// joinFilter joins the non-blank lines of value, such as the items of a list, with arg or ", "
+func joinFilter(value, arg string) (string, error) {
+	if arg == "" {
+		arg = ", "
+	}
+	return strings.Join(splitLines(value), arg), nil
+}
+
+// indentFilter indents every non-blank line by arg spaces, 2 by default
+func indentFilter(value, arg string) (string, error) {
+	width := 2
+	if arg != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(arg))
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid width %q", arg)
+		}
+		width = n
+	}
+	pad := strings.Repeat(" ", width)
+	return prefixLines(value, func(int) string { return pad }), nil
+}
+
+// fenceFilter wraps value in a fenced code block tagged with the language in arg.
+// The fence is made longer than any backtick run inside the value
+func fenceFilter(value, arg string) (string, error) {
+	fence := "```"
+	for strings.Contains(value, fence) {
+		fence += "`"
+	}
+	return fence + strings.TrimSpace(arg) + "\n" + strings.TrimRight(value, "\n") + "\n" + fence, nil
+}
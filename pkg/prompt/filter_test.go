@@ -0,0 +1,94 @@
+package prompt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultParser_Filters(t *testing.T) {
+	parser := NewDefaultParser()
+
+	tests := []struct {
+		name     string
+		content  string
+		values   map[string]any
+		expected string
+	}{
+		{"upper", "${NAME|upper}", map[string]any{"NAME": "ada"}, "ADA"},
+		{"pipeline", "${CODE|trim|fence:go}", map[string]any{"CODE": "\n  x := 1\n\n"}, "```go\nx := 1\n```"},
+		{"fence longer than content", "${CODE|fence}", map[string]any{"CODE": "```sh\nls\n```"}, "````\n```sh\nls\n```\n````"},
+		{"indent", "${TEXT|indent:4}", map[string]any{"TEXT": "a\n\nb"}, "    a\n\n    b"},
+		{"indent default", "${TEXT|indent}", map[string]any{"TEXT": "a"}, "  a"},
+		{"bullets", "${LIST|bullets}", map[string]any{"LIST": "one\ntwo\n"}, "- one\n- two"},
+		{"numbered", "${LIST|numbered}", map[string]any{"LIST": "one\n\ntwo"}, "1. one\n\n2. two"},
+		{"basename", "${PATH|basename}", map[string]any{"PATH": "src/pkg/main.go"}, "main.go"},
+		{"dirname", "${PATH|dirname}", map[string]any{"PATH": "src/pkg/main.go"}, "src/pkg"},
+		{"json", "${TEXT|json}", map[string]any{"TEXT": "say \"hi\"\n"}, `"say \"hi\"\n"`},
+		{"filters apply to defaults", "${LANG:-go|upper}", map[string]any{}, "GO"},
+		{"escaped pipe in default", `${SEP:-a\|b|upper}`, map[string]any{}, "A|B"},
+		{"pipe before a non-filter in default", "${FORMAT:-json|yaml}", map[string]any{}, "json|yaml"},
+		{"pipe before a non-filter with filters", "${FORMAT:-json|yaml|upper}", map[string]any{}, "JSON|YAML"},
+		{"nested placeholder with filter", "${TITLE:-Fix ${ISSUE|upper}|quote}", map[string]any{"ISSUE": "bug-1"}, "> Fix BUG-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.SubstitutePlaceholders(tt.content, tt.values)
+			if err != nil {
+				t.Fatalf("SubstitutePlaceholders() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("SubstitutePlaceholders() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDefaultParser_FilterPlaceholders(t *testing.T) {
+	parser := NewDefaultParser()
+
+	placeholders, err := parser.ParsePlaceholders("${CODE|trim|fence:go} ${LANG:-a\\|b|upper}")
+	if err != nil {
+		t.Fatalf("ParsePlaceholders() error = %v", err)
+	}
+	expected := []Placeholder{
+		{Name: "CODE", Operators: []string{OP_NONE}},
+		{Name: "LANG", DefaultValue: "a|b", HasDefault: true, Operators: []string{OP_DEFAULT}},
+	}
+	if !reflect.DeepEqual(placeholders, expected) {
+		t.Errorf("ParsePlaceholders() = %+v, want %+v", placeholders, expected)
+	}
+
+	placeholders, err = parser.ParsePlaceholders("${FORMAT:-json|yaml}")
+	if err != nil || len(placeholders) != 1 || placeholders[0].DefaultValue != "json|yaml" {
+		t.Errorf("ParsePlaceholders() = %+v, %v; want FORMAT with the default json|yaml", placeholders, err)
+	}
+
+	// An unknown filter makes the placeholder malformed, so it is kept as text
+	if placeholders, err := parser.ParsePlaceholders("Hello\n  ${NAME|shout}"); err != nil || len(placeholders) != 0 {
+		t.Errorf("ParsePlaceholders() = %v, %v; want no placeholders for an unknown filter", placeholders, err)
+	}
+
+	// An empty filter makes the placeholder malformed, so it is kept as text
+	if placeholders, err := parser.ParsePlaceholders("${NAME|}"); err != nil || len(placeholders) != 0 {
+		t.Errorf("ParsePlaceholders() = %v, %v; want no placeholders for an empty filter", placeholders, err)
+	}
+}
+
+func TestFilterRegistry_Custom(t *testing.T) {
+	parser := NewDefaultParser()
+	parser.Filters.Register("wrap", FilterFunc(func(value, arg string) (string, error) {
+		return arg + value + arg, nil
+	}))
+
+	result, err := parser.SubstitutePlaceholders("${NAME|wrap:**}", map[string]any{"NAME": "bold"})
+	if err != nil || result != "**bold**" {
+		t.Errorf("SubstitutePlaceholders() = %q, %v, want %q", result, err, "**bold**")
+	}
+
+	_, err = parser.SubstitutePlaceholders("${NAME|upper:x}", map[string]any{"NAME": "a"})
+	if err == nil || !strings.Contains(err.Error(), "placeholder NAME: filter upper: takes no argument") {
+		t.Errorf("SubstitutePlaceholders() error = %v", err)
+	}
+}
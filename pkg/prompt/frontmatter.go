@@ -0,0 +1,104 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Frontmatter is the optional YAML block at the top of a stored prompt file
+type Frontmatter struct {
+	Engine    string `yaml:"engine"`  // template engine of the body; empty means native
+	Extends   string `yaml:"extends"` // prompt whose blocks this prompt overrides
+	Variables Schema `yaml:"variables"`
+	Metadata  `yaml:",inline"`
+}
+
+// Metadata describes a prompt for listings and pickers
+type Metadata struct {
+	Description string         `yaml:"description"`
+	Tags        []string       `yaml:"tags"`
+	Author      string         `yaml:"author"`
+	Version     string         `yaml:"version"`
+	Model       string         `yaml:"model"` // model the prompt was written for
+	Defaults    map[string]any `yaml:"-"`     // placeholder values decoded like DecodeValues
+}
+
+// ApplyDefaults fills the default of each placeholder that has no inline
+// default from the defaults: block of the frontmatter
+func (m Metadata) ApplyDefaults(placeholders []Placeholder) []Placeholder {
+	for i, placeholder := range placeholders {
+		value, ok := m.Defaults[placeholder.Name]
+		if !ok || placeholder.HasDefault {
+			continue
+		}
+		placeholders[i].DefaultValue = FormatValue(value)
+		placeholders[i].HasDefault = true
+	}
+	return placeholders
+}
+
+// ParseFrontmatter splits an optional leading YAML frontmatter block off a
+// stored prompt. Content without frontmatter is returned unchanged as body
+func ParseFrontmatter(content string) (*Frontmatter, string, error) {
+	frontmatter := &Frontmatter{}
+
+	yamlText, body, found := splitFrontmatter(content)
+	if !found {
+		return frontmatter, content, nil
+	}
+
+	if strings.TrimSpace(yamlText) != "" {
+		if err := yaml.Unmarshal([]byte(yamlText), frontmatter); err != nil {
+			return nil, "", fmt.Errorf("invalid prompt frontmatter: %w", err)
+		}
+		defaults, err := decodeDefaults(yamlText)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid prompt frontmatter: %w", err)
+		}
+		frontmatter.Defaults = defaults
+	}
+
+	if err := frontmatter.Variables.Check(); err != nil {
+		return nil, "", err
+	}
+
+	return frontmatter, body, nil
+}
+
+// decodeDefaults decodes the defaults: block, keeping scalars as written
+func decodeDefaults(yamlText string) (map[string]any, error) {
+	var block struct {
+		Defaults yaml.Node `yaml:"defaults"`
+	}
+	if err := yaml.Unmarshal([]byte(yamlText), &block); err != nil {
+		return nil, err
+	}
+	if block.Defaults.Kind == 0 {
+		return nil, nil
+	}
+	if block.Defaults.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: defaults must map placeholder names to values", block.Defaults.Line)
+	}
+	defaults, _ := decodeNode(&block.Defaults).(map[string]any)
+	return defaults, nil
+}
+
+// splitFrontmatter returns the YAML between the leading --- delimiters and the
+// body after them; found is false if content does not start with a closed block
+func splitFrontmatter(content string) (yamlText, body string, found bool) {
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return "", content, false
+	}
+
+	lines := strings.Split(normalized, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] == "---" {
+			return strings.Join(lines[1:i], "\n"), strings.Join(lines[i+1:], "\n"), true
+		}
+	}
+
+	return "", content, false
+}
@@ -0,0 +1,125 @@
+package prompt
+
+import (
+	"testing"
+)
+
+func TestParseFrontmatter(t *testing.T) {
+	content := `---
+variables:
+  LANGUAGE:
+    type: enum
+    description: Programming language
+    values: [Go, Python]
+    required: true
+---
+Review this ${LANGUAGE} code.`
+
+	frontmatter, body, err := ParseFrontmatter(content)
+	if err != nil {
+		t.Fatalf("ParseFrontmatter() error = %v", err)
+	}
+
+	if body != "Review this ${LANGUAGE} code." {
+		t.Errorf("ParseFrontmatter() body = %q", body)
+	}
+
+	spec, ok := frontmatter.Variables["LANGUAGE"]
+	if !ok {
+		t.Fatal("ParseFrontmatter() did not parse the LANGUAGE variable")
+	}
+	if spec.Type != TYPE_ENUM || !spec.Required || len(spec.Values) != 2 || spec.Description != "Programming language" {
+		t.Errorf("ParseFrontmatter() LANGUAGE spec = %+v", spec)
+	}
+}
+
+func TestParseFrontmatterWithoutBlock(t *testing.T) {
+	for _, content := range []string{"Plain prompt", "---\nunclosed", "Text\n---\nmore"} {
+		frontmatter, body, err := ParseFrontmatter(content)
+		if err != nil {
+			t.Fatalf("ParseFrontmatter(%q) error = %v", content, err)
+		}
+		if body != content {
+			t.Errorf("ParseFrontmatter(%q) body = %q, want content unchanged", content, body)
+		}
+		if len(frontmatter.Variables) != 0 {
+			t.Errorf("ParseFrontmatter(%q) variables = %v, want none", content, frontmatter.Variables)
+		}
+	}
+}
+
+func TestParseFrontmatterInvalid(t *testing.T) {
+	for _, content := range []string{
+		"---\nvariables: [unclosed\n---\nbody",
+		"---\nvariables:\n  A:\n    type: float\n---\nbody",
+	} {
+		if _, _, err := ParseFrontmatter(content); err == nil {
+			t.Errorf("ParseFrontmatter(%q) expected error", content)
+		}
+	}
+}
+
+func TestParseFrontmatterMetadata(t *testing.T) {
+	content := `---
+description: Review a pull request
+tags: [review, go]
+author: ada
+version: 1.10
+model: gpt-4o
+defaults:
+  LANGUAGE: Go
+  COUNT: 3
+  FILES: [a.go, b.go]
+---
+Review ${FILES} in ${LANGUAGE}.`
+
+	frontmatter, body, err := ParseFrontmatter(content)
+	if err != nil {
+		t.Fatalf("ParseFrontmatter() error = %v", err)
+	}
+	if body != "Review ${FILES} in ${LANGUAGE}." {
+		t.Errorf("ParseFrontmatter() body = %q", body)
+	}
+
+	metadata := frontmatter.Metadata
+	if metadata.Description != "Review a pull request" || metadata.Author != "ada" || metadata.Version != "1.10" || metadata.Model != "gpt-4o" {
+		t.Errorf("ParseFrontmatter() metadata = %+v", metadata)
+	}
+	if len(metadata.Tags) != 2 || metadata.Tags[0] != "review" || metadata.Tags[1] != "go" {
+		t.Errorf("ParseFrontmatter() tags = %v", metadata.Tags)
+	}
+	if metadata.Defaults["COUNT"] != "3" || metadata.Defaults["LANGUAGE"] != "Go" {
+		t.Errorf("ParseFrontmatter() defaults = %#v", metadata.Defaults)
+	}
+
+	if _, _, err := ParseFrontmatter("---\ndefaults: [a, b]\n---\nbody"); err == nil {
+		t.Error("ParseFrontmatter() expected error for defaults that are not a mapping")
+	}
+}
+
+func TestMetadataApplyDefaults(t *testing.T) {
+	metadata := Metadata{Defaults: map[string]any{
+		"LANGUAGE": "Go",
+		"FILES":    []any{"a.go", "b.go"},
+		"FOCUS":    "style",
+	}}
+	placeholders := metadata.ApplyDefaults([]Placeholder{
+		{Name: "LANGUAGE"},
+		{Name: "FILES"},
+		{Name: "FOCUS", DefaultValue: "bugs", HasDefault: true},
+		{Name: "OTHER"},
+	})
+
+	expected := []Placeholder{
+		{Name: "LANGUAGE", DefaultValue: "Go", HasDefault: true},
+		{Name: "FILES", DefaultValue: "a.go\nb.go", HasDefault: true},
+		{Name: "FOCUS", DefaultValue: "bugs", HasDefault: true},
+		{Name: "OTHER"},
+	}
+	for i, want := range expected {
+		got := placeholders[i]
+		if got.Name != want.Name || got.DefaultValue != want.DefaultValue || got.HasDefault != want.HasDefault {
+			t.Errorf("ApplyDefaults()[%d] = %+v, want %+v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package prompt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+var (
+	// ErrRevisionNotFound is returned when no revision of a prompt matches a revision ID
+	ErrRevisionNotFound = errors.New("revision not found")
+	// ErrAmbiguousRevision is returned when a revision ID prefix matches several revisions
+	ErrAmbiguousRevision = errors.New("ambiguous revision")
+)
+
+// REVISION_ID_LENGTH is the number of hash characters shown as a revision ID
+const REVISION_ID_LENGTH = 12
+
+// Revision is a snapshot of a prompt's content taken before a command changed it
+type Revision struct {
+	Hash   string    `json:"hash"` // sha256 of the content
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"` // the command that changed the prompt, e.g. edit or rm
+	Source string    `json:"source"`
+	Path   string    `json:"path"` // absolute path of the prompt file
+}
+
+// ID returns the abbreviated hash identifying the revision
+func (r Revision) ID() string {
+	return r.Hash[:min(REVISION_ID_LENGTH, len(r.Hash))]
+}
+
+// History is a content-addressed store of prompt snapshots. Contents are
+// stored once under objects/ by their hash; logs/ holds a log of revisions per
+// prompt file, keyed by its absolute path without the extension, so prompts of
+// the same name in other projects have a history of their own
+type History struct {
+	Filesystem filesystem.Filesystem
+	Resolver   LocationResolver // levels whose prompts Log and Find look at
+	Dir        string
+	Now        func() time.Time
+}
+
+// NewHistory creates a History in $XDG_DATA_HOME/proompt/history
+func NewHistory(fs filesystem.Filesystem, resolver LocationResolver) (*History, error) {
+	dataDir, err := fs.UserDataDir()
+	if err != nil {
+		return nil, err
+	}
+	return &History{
+		Filesystem: fs,
+		Resolver:   resolver,
+		Dir:        filepath.Join(dataDir, "proompt", "history"),
+		Now:        time.Now,
+	}, nil
+}
+
+// Record snapshots the content of info under its absolute path. A snapshot
+// identical to the latest revision of the prompt file is not recorded twice
+func (h *History) Record(info *PromptInfo, action string) (*Revision, error) {
+	promptPath, err := filepath.Abs(info.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", info.Path, err)
+	}
+
+	sum := sha256.Sum256([]byte(info.Content))
+	revision := Revision{
+		Hash:   hex.EncodeToString(sum[:]),
+		Time:   h.Now().UTC(),
+		Action: action,
+		Source: info.Source,
+		Path:   promptPath,
+	}
+
+	key := removeExtension(promptPath)
+	revisions, err := h.read(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) > 0 && revisions[len(revisions)-1].Hash == revision.Hash {
+		return &revisions[len(revisions)-1], nil
+	}
+
+	objectPath := h.objectPath(revision.Hash)
+	if _, err := h.Filesystem.Stat(objectPath); err != nil {
+		if err := h.write(objectPath, []byte(info.Content)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.store(key, append(revisions, revision)); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Log returns the revisions of the prompts called name at the levels of
+// h.Resolver, newest first
+func (h *History) Log(name string) ([]Revision, error) {
+	locations, err := h.Resolver.GetPromptPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []Revision
+	seen := make(map[string]bool)
+	for _, location := range locations {
+		key := absolutePath(location.Path) + "/" + name
+		if seen[key] {
+			continue // e.g. the directory level is the project level at the project root
+		}
+		seen[key] = true
+
+		found, err := h.read(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid history of %s: %w", name, err)
+		}
+		revisions = append(revisions, found...)
+	}
+
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return revisions[i].Time.After(revisions[j].Time)
+	})
+	return revisions, nil
+}
+
+// Move carries the revisions of the prompt file oldPath over to newPath, e.g.
+// when the prompt is renamed. They are merged with any revisions of newPath
+func (h *History) Move(oldPath, newPath string) error {
+	oldKey, newKey := removeExtension(absolutePath(oldPath)), removeExtension(absolutePath(newPath))
+	if oldKey == newKey {
+		return nil
+	}
+
+	moved, err := h.read(oldKey)
+	if err != nil || len(moved) == 0 {
+		return err
+	}
+	revisions, err := h.read(newKey)
+	if err != nil {
+		return err
+	}
+	revisions = append(revisions, moved...)
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return revisions[i].Time.Before(revisions[j].Time)
+	})
+
+	if err := h.store(newKey, revisions); err != nil {
+		return err
+	}
+	return h.Filesystem.Remove(h.logPath(oldKey))
+}
+
+// Find returns the revision of the prompts called name whose hash starts with id
+func (h *History) Find(name, id string) (*Revision, error) {
+	revisions, err := h.Log(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *Revision
+	for i, revision := range revisions {
+		if id == "" || !strings.HasPrefix(revision.Hash, id) {
+			continue
+		}
+		if found != nil && found.Hash != revision.Hash {
+			return nil, fmt.Errorf("%w %q of %s", ErrAmbiguousRevision, id, name)
+		}
+		if found == nil {
+			found = &revisions[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s@%s", ErrRevisionNotFound, name, id)
+	}
+	return found, nil
+}
+
+// Ancestor returns the newest content the prompt files of a and b had in
+// common: the current content of a if b ever had it, or else the latest
+// revision of a that matches b's content or one of its revisions. It reports
+// false if the two never shared any content
+func (h *History) Ancestor(a, b *PromptInfo) (string, bool, error) {
+	revisionsA, err := h.read(removeExtension(absolutePath(a.Path)))
+	if err != nil {
+		return "", false, err
+	}
+	revisionsB, err := h.read(removeExtension(absolutePath(b.Path)))
+	if err != nil {
+		return "", false, err
+	}
+
+	sum := sha256.Sum256([]byte(b.Content))
+	hashesB := map[string]bool{hex.EncodeToString(sum[:]): true}
+	for _, revision := range revisionsB {
+		hashesB[revision.Hash] = true
+	}
+
+	sum = sha256.Sum256([]byte(a.Content))
+	if hashesB[hex.EncodeToString(sum[:])] {
+		return a.Content, true, nil
+	}
+	for i := len(revisionsA) - 1; i >= 0; i-- {
+		if hashesB[revisionsA[i].Hash] {
+			content, err := h.Content(&revisionsA[i])
+			if err != nil {
+				return "", false, err
+			}
+			return content, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Content returns the prompt content stored for revision
+func (h *History) Content(revision *Revision) (string, error) {
+	data, err := h.Filesystem.ReadFile(h.objectPath(revision.Hash))
+	if err != nil {
+		return "", fmt.Errorf("content of revision %s is missing: %w", revision.ID(), err)
+	}
+	return string(data), nil
+}
+
+// read returns the revisions of the prompt file key, oldest first
+func (h *History) read(key string) ([]Revision, error) {
+	data, err := h.Filesystem.ReadFile(h.logPath(key))
+	if err != nil {
+		return nil, nil // No revisions yet
+	}
+
+	var revisions []Revision
+	if err := json.Unmarshal(data, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// store writes the revisions of the prompt file key, oldest first
+func (h *History) store(key string, revisions []Revision) error {
+	data, err := json.MarshalIndent(revisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return h.write(h.logPath(key), data)
+}
+
+// write writes data to file, creating its directory
+func (h *History) write(file string, data []byte) error {
+	if err := h.Filesystem.MkdirAll(path.Dir(file), 0755); err != nil {
+		return err
+	}
+	return h.Filesystem.WriteFile(file, data, 0644)
+}
+
+// objectPath returns the path of the content with the given hash
+func (h *History) objectPath(hash string) string {
+	return h.Dir + "/objects/" + hash[:2] + "/" + hash[2:]
+}
+
+// logPath returns the path of the revision log of the prompt file key, its
+// absolute path without the extension
+func (h *History) logPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return h.Dir + "/logs/" + hex.EncodeToString(sum[:8]) + ".json"
+}
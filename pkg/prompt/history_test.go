@@ -0,0 +1,115 @@
+package prompt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+func TestHistory(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{{Type: "project", Path: "/project"}, {Type: "user", Path: "/user"}}
+	history := &History{
+		Filesystem: filesystem.NewRealFilesystem("/"),
+		Resolver:   resolver,
+		Dir:        t.TempDir(),
+		Now: func() time.Time {
+			now = now.Add(time.Minute)
+			return now
+		},
+	}
+
+	if revisions, err := history.Log("git/commit"); err != nil || len(revisions) != 0 {
+		t.Fatalf("Log() without history = %v, %v", revisions, err)
+	}
+
+	snapshots := []struct {
+		source, content, action string
+	}{
+		{"user", "v1", "edit"},
+		{"user", "v1", "edit"}, // unchanged, not recorded again
+		{"project", "v1", "mv"},
+		{"user", "v2", "rm"},
+	}
+	for _, snapshot := range snapshots {
+		info := &PromptInfo{Name: "git/commit", Source: snapshot.source, Path: "/" + snapshot.source + "/git/commit.md", Content: snapshot.content}
+		if _, err := history.Record(info, snapshot.action); err != nil {
+			t.Fatalf("Record() failed: %v", err)
+		}
+	}
+
+	revisions, err := history.Log("git/commit")
+	if err != nil {
+		t.Fatalf("Log() failed: %v", err)
+	}
+	var actions []string
+	for _, revision := range revisions {
+		actions = append(actions, revision.Source+":"+revision.Action)
+	}
+	if len(actions) != 3 || actions[0] != "user:rm" || actions[1] != "project:mv" || actions[2] != "user:edit" {
+		t.Fatalf("Log() = %v, want newest first without the duplicate", actions)
+	}
+
+	revision, err := history.Find("git/commit", revisions[0].ID())
+	if err != nil {
+		t.Fatalf("Find() failed: %v", err)
+	}
+	if content, err := history.Content(revision); err != nil || content != "v2" {
+		t.Errorf("Content() = %q, %v, want v2", content, err)
+	}
+
+	// Revisions with the same content share their ID and are not ambiguous
+	if revision, err := history.Find("git/commit", revisions[2].ID()[:4]); err != nil || revision.Action != "mv" {
+		t.Errorf("Find() of shared content = %+v, %v", revision, err)
+	}
+	if _, err := history.Find("git/commit", "zzzz"); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("Find() error = %v, want ErrRevisionNotFound", err)
+	}
+
+	// A prompt of the same name in another project has a history of its own
+	other := &PromptInfo{Name: "git/commit", Source: "project", Path: "/other/git/commit.md", Content: "v3"}
+	if _, err := history.Record(other, "edit"); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+	if revisions, _ := history.Log("git/commit"); len(revisions) != 3 {
+		t.Errorf("Log() = %+v, want the revisions of this project only", revisions)
+	}
+	resolver.Locations = []PromptLocation{{Type: "project", Path: "/other"}}
+	if revisions, _ := history.Log("git/commit"); len(revisions) != 1 || revisions[0].Path != "/other/git/commit.md" {
+		t.Errorf("Log() in the other project = %+v, want its edit only", revisions)
+	}
+	if _, err := history.Find("git/commit", revision.ID()); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("Find() of a revision of another project error = %v, want ErrRevisionNotFound", err)
+	}
+}
+
+func TestHistoryAncestor(t *testing.T) {
+	history := &History{Filesystem: filesystem.NewRealFilesystem("/"), Resolver: NewFakeLocationResolver(), Dir: t.TempDir(), Now: time.Now}
+	user := &PromptInfo{Name: "review", Source: "user", Path: "/user/review.md", Content: "v1"}
+	project := &PromptInfo{Name: "review", Source: "project", Path: "/project/review.md", Content: "other"}
+
+	if _, ok, err := history.Ancestor(user, project); err != nil || ok {
+		t.Errorf("Ancestor() without shared content = %v, %v, want none", ok, err)
+	}
+
+	// Both prompts were v1 before they changed
+	for _, info := range []*PromptInfo{user, project} {
+		info.Content = "v1"
+		if _, err := history.Record(info, "edit"); err != nil {
+			t.Fatalf("Record() failed: %v", err)
+		}
+	}
+	user.Content, project.Content = "v2", "v3"
+	if base, ok, err := history.Ancestor(user, project); err != nil || !ok || base != "v1" {
+		t.Errorf("Ancestor() = %q, %v, %v, want v1", base, ok, err)
+	}
+
+	// The project prompt was copied from the current user prompt
+	project.Content = "v2"
+	if base, ok, err := history.Ancestor(user, project); err != nil || !ok || base != "v2" {
+		t.Errorf("Ancestor() of the same content = %q, %v, %v, want v2", base, ok, err)
+	}
+}
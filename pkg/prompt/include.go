@@ -0,0 +1,90 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrIncludeCycle is returned when prompts include each other
+var ErrIncludeCycle = errors.New("include cycle")
+
+// IncludeResolver expands ${@include:name} directives by looking up the named
+// prompts through a Manager, so includes follow the normal prompt hierarchy
+type IncludeResolver struct {
+	Manager Manager
+}
+
+// NewIncludeResolver creates a new IncludeResolver
+func NewIncludeResolver(manager Manager) *IncludeResolver {
+	return &IncludeResolver{
+		Manager: manager,
+	}
+}
+
+// Expand replaces all include directives in content, recursively. name is the
+// prompt the content belongs to and starts the include chain; it may be empty
+func (r *IncludeResolver) Expand(name, content string) (string, error) {
+	var chain []string
+	if name != "" {
+		chain = []string{name}
+	}
+	return r.expand(content, chain)
+}
+
+// expand replaces include directives in content; chain holds the names of the
+// prompts currently being expanded, outermost first
+func (r *IncludeResolver) expand(content string, chain []string) (string, error) {
+	var result strings.Builder
+
+	for _, node := range ParseTemplate(content).Nodes {
+		directive, ok := node.(*DirectiveNode)
+		if !ok || directive.Name != "include" {
+			result.WriteString(node.Source())
+			continue
+		}
+
+		name := directive.Arg
+		for _, seen := range chain {
+			if seen == name {
+				return "", fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(append(chain, name), " -> "))
+			}
+		}
+
+		included, err := r.Manager.Get(name)
+		if err != nil {
+			return "", includeError(chain, name, err)
+		}
+
+		_, body, err := NewExtendsResolver(r.Manager).Parse(included)
+		if err != nil {
+			return "", includeError(chain, name, err)
+		}
+		// A chat prompt is included in its flattened markdown form
+		if IsChatPrompt(included.Path) {
+			messages, err := ParseMessages(body)
+			if err == nil {
+				body, err = FormatMessages(messages, FORMAT_MARKDOWN)
+			}
+			if err != nil {
+				return "", includeError(chain, name, err)
+			}
+		}
+
+		expanded, err := r.expand(body, append(chain[:len(chain):len(chain)], name))
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(expanded)
+	}
+
+	return result.String(), nil
+}
+
+// includeError describes a failed include together with the chain that led to it
+func includeError(chain []string, name string, err error) error {
+	if len(chain) == 0 {
+		return fmt.Errorf("failed to include '%s': %w", name, err)
+	}
+	return fmt.Errorf("failed to include '%s' (via %s): %w", name, strings.Join(chain, " -> "), err)
+}
@@ -0,0 +1,90 @@
+package prompt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+func newIncludeTestManager(files map[string]string) *DefaultManager {
+	fs := filesystem.NewFakeFilesystem()
+	for path, content := range files {
+		fs.MapFS[path] = &fstest.MapFile{Data: []byte(content), Mode: 0644}
+	}
+
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{
+		{Type: "project", Path: "prompts"},
+		{Type: "user", Path: "user/prompts"},
+	}
+
+	return NewDefaultManager(fs, resolver)
+}
+
+func TestIncludeResolverExpand(t *testing.T) {
+	manager := newIncludeTestManager(map[string]string{
+		"prompts/review.md":           "Review ${CODE}.\n${@include:conventions}\n${@include:format}",
+		"prompts/conventions.md":      "Project conventions for ${LANGUAGE:-Go}",
+		"user/prompts/conventions.md": "User conventions",
+		"user/prompts/format.md":      "Answer in ${FORMAT:-markdown}. ${@include:footer}",
+		"user/prompts/footer.md":      "Thanks!",
+	})
+	resolver := NewIncludeResolver(manager)
+
+	result, err := resolver.Expand("review", "Review ${CODE}.\n${@include:conventions}\n${@include:format}")
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+
+	expected := "Review ${CODE}.\nProject conventions for ${LANGUAGE:-Go}\nAnswer in ${FORMAT:-markdown}. Thanks!"
+	if result != expected {
+		t.Errorf("Expand() = %q, want %q", result, expected)
+	}
+}
+
+func TestIncludeResolverEscapedDirective(t *testing.T) {
+	resolver := NewIncludeResolver(newIncludeTestManager(nil))
+
+	result, err := resolver.Expand("", "Literal $${@include:missing}")
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if result != "Literal $${@include:missing}" {
+		t.Errorf("Expand() = %q, escaped directive should be left alone", result)
+	}
+}
+
+func TestIncludeResolverCycle(t *testing.T) {
+	manager := newIncludeTestManager(map[string]string{
+		"prompts/a.md": "A ${@include:b}",
+		"prompts/b.md": "B ${@include:c}",
+		"prompts/c.md": "C ${@include:a}",
+	})
+	resolver := NewIncludeResolver(manager)
+
+	_, err := resolver.Expand("a", "A ${@include:b}")
+	if !errors.Is(err, ErrIncludeCycle) {
+		t.Fatalf("Expand() error = %v, want ErrIncludeCycle", err)
+	}
+	if !strings.Contains(err.Error(), "a -> b -> c -> a") {
+		t.Errorf("Expand() error %q should contain the include chain", err)
+	}
+}
+
+func TestIncludeResolverMissingPrompt(t *testing.T) {
+	manager := newIncludeTestManager(map[string]string{
+		"prompts/a.md": "A ${@include:missing}",
+	})
+	resolver := NewIncludeResolver(manager)
+
+	_, err := resolver.Expand("root", "${@include:a}")
+	if !errors.Is(err, ErrPromptNotFound) {
+		t.Fatalf("Expand() error = %v, want ErrPromptNotFound", err)
+	}
+	if !strings.Contains(err.Error(), "'missing' (via root -> a)") {
+		t.Errorf("Expand() error %q should name the include chain", err)
+	}
+}
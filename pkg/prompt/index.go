@@ -0,0 +1,139 @@
+package prompt
+
+import (
+	"encoding/json"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+// INDEX_VERSION is bumped whenever the meaning of cached entries changes, discarding older indexes
+const INDEX_VERSION = 1
+
+// Index caches the metadata of prompt files on disk, keyed by path and
+// invalidated when a file's modification time or size changes. It lets the
+// catalog list prompts without reading files that have not changed
+type Index struct {
+	Filesystem filesystem.Filesystem
+	Path       string
+
+	mu      sync.Mutex
+	loaded  bool
+	dirty   bool
+	entries map[string]indexEntry
+}
+
+// indexEntry is the cached metadata of one prompt file
+type indexEntry struct {
+	ModTime  time.Time `json:"mtime"`
+	Size     int64     `json:"size"`
+	Metadata Metadata  `json:"metadata"`
+}
+
+// indexFile is the on-disk format of an Index
+type indexFile struct {
+	Version int                   `json:"version"`
+	Entries map[string]indexEntry `json:"entries"`
+}
+
+// NewIndex creates an Index stored in $XDG_CACHE_HOME/proompt/index.json
+func NewIndex(fs filesystem.Filesystem) (*Index, error) {
+	cacheDir, err := fs.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Index{
+		Filesystem: fs,
+		Path:       filepath.Join(cacheDir, "proompt", "index.json"),
+	}, nil
+}
+
+// Lookup returns the cached metadata of the file at path if info matches the cached modification time and size
+func (x *Index) Lookup(path string, info fs.FileInfo) (Metadata, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.load()
+
+	entry, ok := x.entries[path]
+	if !ok || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+		return Metadata{}, false
+	}
+	return entry.Metadata, true
+}
+
+// Update caches the metadata of the file at path
+func (x *Index) Update(path string, info fs.FileInfo, metadata Metadata) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.load()
+
+	x.entries[path] = indexEntry{ModTime: info.ModTime(), Size: info.Size(), Metadata: metadata}
+	x.dirty = true
+}
+
+// Retain drops the entries below the directories roots whose paths are not in seen,
+// i.e. prompt files that were removed since they were cached
+func (x *Index) Retain(roots []string, seen map[string]bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.load()
+
+	for path := range x.entries {
+		if seen[path] {
+			continue
+		}
+		for _, root := range roots {
+			if strings.HasPrefix(path, root+"/") {
+				delete(x.entries, path)
+				x.dirty = true
+				break
+			}
+		}
+	}
+}
+
+// Save writes the index if it changed. The index is a cache, so callers may ignore errors
+func (x *Index) Save() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(indexFile{Version: INDEX_VERSION, Entries: x.entries})
+	if err != nil {
+		return err
+	}
+	if err := x.Filesystem.MkdirAll(path.Dir(x.Path), 0755); err != nil {
+		return err
+	}
+	if err := x.Filesystem.WriteFile(x.Path, data, 0644); err != nil {
+		return err
+	}
+	x.dirty = false
+	return nil
+}
+
+// load reads the index on first use. A missing, unreadable or outdated index starts out empty
+func (x *Index) load() {
+	if x.loaded {
+		return
+	}
+	x.loaded = true
+	x.entries = make(map[string]indexEntry)
+
+	data, err := x.Filesystem.ReadFile(x.Path)
+	if err != nil {
+		return
+	}
+	var file indexFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != INDEX_VERSION || file.Entries == nil {
+		return
+	}
+	x.entries = file.Entries
+}
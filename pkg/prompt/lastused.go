@@ -0,0 +1,151 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+// LAST_USED_VERSION is bumped whenever the format of the last-used state file changes
+const LAST_USED_VERSION = 1
+
+// LastUsed remembers the placeholder values each prompt was last picked
+// with. Values are kept per prompt name and, if Project is set, per project
+type LastUsed struct {
+	Filesystem filesystem.Filesystem
+	Path       string
+	Project    string // root of the current project; empty keeps one set of values per prompt
+}
+
+// lastUsedEntry is the remembered values of one prompt
+type lastUsedEntry struct {
+	Values   map[string]any            `json:"values,omitempty"`
+	Projects map[string]map[string]any `json:"projects,omitempty"` // project root -> values
+}
+
+// lastUsedFile is the on-disk format of LastUsed
+type lastUsedFile struct {
+	Version int                      `json:"version"`
+	Prompts map[string]lastUsedEntry `json:"prompts"`
+}
+
+// NewLastUsed creates a LastUsed stored in $XDG_STATE_HOME/proompt/last-used.json
+func NewLastUsed(fs filesystem.Filesystem) (*LastUsed, error) {
+	stateDir, err := fs.UserStateDir()
+	if err != nil {
+		return nil, err
+	}
+	return &LastUsed{
+		Filesystem: fs,
+		Path:       filepath.Join(stateDir, "proompt", "last-used.json"),
+	}, nil
+}
+
+// Get returns the values the prompt name was last used with, preferring the
+// values used in the current project
+func (l *LastUsed) Get(name string) (map[string]any, error) {
+	file, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := file.Prompts[name]
+	if values, ok := entry.Projects[l.Project]; ok && l.Project != "" {
+		return values, nil
+	}
+	return entry.Values, nil
+}
+
+// Save replaces the remembered values of the prompt name
+func (l *LastUsed) Save(name string, values map[string]any) error {
+	return l.update(func(file *lastUsedFile) {
+		entry := file.Prompts[name]
+		if l.Project == "" {
+			entry.Values = values
+		} else {
+			if entry.Projects == nil {
+				entry.Projects = make(map[string]map[string]any)
+			}
+			entry.Projects[l.Project] = values
+			if len(values) == 0 {
+				delete(entry.Projects, l.Project)
+			}
+		}
+
+		if len(entry.Values) == 0 && len(entry.Projects) == 0 {
+			delete(file.Prompts, name)
+			return
+		}
+		file.Prompts[name] = entry
+	})
+}
+
+// Clear forgets the values of the prompts names in all projects, or of
+// every prompt if no names are given. It returns the names of the prompts
+// whose values were forgotten
+func (l *LastUsed) Clear(names ...string) ([]string, error) {
+	var cleared []string
+	err := l.update(func(file *lastUsedFile) {
+		if len(names) == 0 {
+			for name := range file.Prompts {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+		for _, name := range names {
+			if _, ok := file.Prompts[name]; ok {
+				delete(file.Prompts, name)
+				cleared = append(cleared, name)
+			}
+		}
+	})
+	return cleared, err
+}
+
+// update applies change to the state file while holding its lock
+func (l *LastUsed) update(change func(file *lastUsedFile)) error {
+	if err := l.Filesystem.MkdirAll(path.Dir(l.Path), 0755); err != nil {
+		return err
+	}
+	unlock, err := l.Filesystem.Lock(l.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := l.read()
+	if err != nil {
+		return err
+	}
+	change(file)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return l.Filesystem.WriteFile(l.Path, data, 0644)
+}
+
+// read loads the state file; a missing file holds no values
+func (l *LastUsed) read() (*lastUsedFile, error) {
+	file := &lastUsedFile{Version: LAST_USED_VERSION, Prompts: make(map[string]lastUsedEntry)}
+
+	data, err := l.Filesystem.ReadFile(l.Path)
+	if err != nil {
+		return file, nil // Nothing remembered yet
+	}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("invalid last-used values in %s: %w", l.Path, err)
+	}
+	if file.Version != LAST_USED_VERSION {
+		return nil, fmt.Errorf("invalid last-used values in %s: unsupported version %d", l.Path, file.Version)
+	}
+	if file.Prompts == nil {
+		file.Prompts = make(map[string]lastUsedEntry)
+	}
+	return file, nil
+}
@@ -0,0 +1,98 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+func TestLastUsed(t *testing.T) {
+	fs := filesystem.NewRealFilesystem("/")
+	global := &LastUsed{Filesystem: fs, Path: t.TempDir() + "/proompt/last-used.json"}
+	project := &LastUsed{Filesystem: fs, Path: global.Path, Project: "/work/app"}
+
+	if values, err := global.Get("review"); err != nil || values != nil {
+		t.Fatalf("Get() before Save() = %v, %v; want no values", values, err)
+	}
+
+	if err := global.Save("review", map[string]any{"LANGUAGE": "Go"}); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if err := project.Save("review", map[string]any{"LANGUAGE": "Rust", "FILES": []any{"main.rs"}}); err != nil {
+		t.Fatalf("Save() in a project failed: %v", err)
+	}
+	if err := global.Save("summary", map[string]any{"LENGTH": "short"}); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		lastUsed *LastUsed
+		prompt   string
+		want     map[string]any
+	}{
+		{name: "global values", lastUsed: global, prompt: "review", want: map[string]any{"LANGUAGE": "Go"}},
+		{name: "project values win", lastUsed: project, prompt: "review", want: map[string]any{"LANGUAGE": "Rust", "FILES": []any{"main.rs"}}},
+		{name: "global values outside the project", lastUsed: &LastUsed{Filesystem: fs, Path: global.Path, Project: "/work/other"}, prompt: "review", want: map[string]any{"LANGUAGE": "Go"}},
+		{name: "global fallback in a project", lastUsed: project, prompt: "summary", want: map[string]any{"LENGTH": "short"}},
+		{name: "unknown prompt", lastUsed: global, prompt: "missing", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := tt.lastUsed.Get(tt.prompt)
+			if err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			if !reflect.DeepEqual(values, tt.want) {
+				t.Errorf("Get(%q) = %v, want %v", tt.prompt, values, tt.want)
+			}
+		})
+	}
+
+	if err := project.Save("review", nil); err != nil {
+		t.Fatalf("Save() of no values failed: %v", err)
+	}
+	if values, _ := project.Get("review"); !reflect.DeepEqual(values, map[string]any{"LANGUAGE": "Go"}) {
+		t.Errorf("Get() after saving no values = %v, want the global values", values)
+	}
+
+	cleared, err := global.Clear("review", "missing")
+	if err != nil || !reflect.DeepEqual(cleared, []string{"review"}) {
+		t.Errorf("Clear(review, missing) = %v, %v; want [review]", cleared, err)
+	}
+	if values, _ := global.Get("review"); values != nil {
+		t.Errorf("Get() after Clear() = %v, want no values", values)
+	}
+
+	cleared, err = global.Clear()
+	if err != nil || !reflect.DeepEqual(cleared, []string{"summary"}) {
+		t.Errorf("Clear() = %v, %v; want [summary]", cleared, err)
+	}
+}
+
+func TestLastUsedInvalidFile(t *testing.T) {
+	fs := filesystem.NewRealFilesystem("/")
+	lastUsed := &LastUsed{Filesystem: fs, Path: t.TempDir() + "/last-used.json"}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "invalid json", content: "{"},
+		{name: "unsupported version", content: `{"version": 99, "prompts": {}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := fs.WriteFile(lastUsed.Path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := lastUsed.Get("review"); err == nil {
+				t.Error("Get() succeeded, want an error")
+			}
+			if err := lastUsed.Save("review", map[string]any{"A": "b"}); err == nil {
+				t.Error("Save() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MustacheEngine renders a logic-less mustache subset: {{NAME}}, sections
+// {{#NAME}}...{{/NAME}}, inverted sections {{^NAME}}...{{/NAME}} and comments {{! ... }}.
+// Values are not HTML-escaped, so {{{NAME}}} and {{&NAME}} behave like {{NAME}}.
+// A section over a list, or a multi-line string, repeats once per item with the item as {{.}};
+// a section over a map makes its keys available as names
+type MustacheEngine struct{}
+
+// NewMustacheEngine creates a new MustacheEngine
+func NewMustacheEngine() *MustacheEngine {
+	return &MustacheEngine{}
+}
+
+// mustacheKind identifies a mustache tag or text segment
+type mustacheKind int
+
+const (
+	mustacheText mustacheKind = iota
+	mustacheVariable
+	mustacheSection
+	mustacheInverted
+	mustacheClose
+	mustacheComment
+)
+
+// mustacheNode is a parsed segment of a mustache template
+type mustacheNode struct {
+	kind     mustacheKind
+	value    string // literal text or tag name
+	line     int
+	children []*mustacheNode
+}
+
+// Variables implements Engine
+func (e *MustacheEngine) Variables(content string) ([]Placeholder, error) {
+	nodes, err := parseMustache(content)
+	if err != nil {
+		return nil, err
+	}
+
+	collector := newVariableCollector()
+	var walk func(nodes []*mustacheNode)
+	walk = func(nodes []*mustacheNode) {
+		for _, node := range nodes {
+			if node.kind != mustacheText && node.value != "." && !IsBuiltin(node.value) {
+				collector.add(node.value)
+			}
+			walk(node.children)
+		}
+	}
+	walk(nodes)
+
+	return collector.placeholders, nil
+}
+
+// Render implements Engine
+func (e *MustacheEngine) Render(content string, values map[string]any) (string, error) {
+	nodes, err := parseMustache(content)
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	renderMustache(&buf, nodes, []any{values})
+	return buf.String(), nil
+}
+
+// renderMustache writes nodes to buf. stack holds the values of the enclosing
+// sections, innermost last; names are looked up from the innermost map outwards
+func renderMustache(buf *strings.Builder, nodes []*mustacheNode, stack []any) {
+	for _, node := range nodes {
+		switch node.kind {
+		case mustacheText:
+			buf.WriteString(node.value)
+		case mustacheVariable:
+			buf.WriteString(FormatValue(lookupMustache(stack, node.value)))
+		case mustacheSection:
+			value := lookupMustache(stack, node.value)
+			if !isTruthy(value) {
+				continue
+			}
+			switch value.(type) {
+			case map[string]any, bool:
+				renderMustache(buf, node.children, append(stack[:len(stack):len(stack)], value))
+				continue
+			}
+			items := listItems(value)
+			if len(items) < 2 {
+				items = []any{value}
+			}
+			for _, item := range items {
+				renderMustache(buf, node.children, append(stack[:len(stack):len(stack)], item))
+			}
+		case mustacheInverted:
+			if !isTruthy(lookupMustache(stack, node.value)) {
+				renderMustache(buf, node.children, stack)
+			}
+		}
+	}
+}
+
+// lookupMustache resolves a tag name against the context stack; "." is the
+// innermost value and a.b looks up b inside the map a unless a.b is a key itself
+func lookupMustache(stack []any, name string) any {
+	if name == "." {
+		return stack[len(stack)-1]
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		context, ok := stack[i].(map[string]any)
+		if !ok {
+			continue
+		}
+		if value, ok := context[name]; ok {
+			return value
+		}
+		first, rest, dotted := strings.Cut(name, ".")
+		if value, ok := context[first]; ok && dotted {
+			return lookupMustache([]any{value}, rest)
+		}
+	}
+	return nil
+}
+
+// parseMustache parses a template into a tree of sections
+func parseMustache(src string) ([]*mustacheNode, error) {
+	tokens, err := tokenizeMustache(src)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &mustacheNode{}
+	stack := []*mustacheNode{root}
+	for _, token := range tokens {
+		parent := stack[len(stack)-1]
+		switch token.kind {
+		case mustacheComment:
+		case mustacheSection, mustacheInverted:
+			parent.children = append(parent.children, token)
+			stack = append(stack, token)
+		case mustacheClose:
+			if parent == root {
+				return nil, fmt.Errorf("invalid mustache template: line %d: {{/%s}} closes no section", token.line, token.value)
+			}
+			if parent.value != token.value {
+				return nil, fmt.Errorf("invalid mustache template: line %d: {{/%s}} does not close {{#%s}} from line %d", token.line, token.value, parent.value, parent.line)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			parent.children = append(parent.children, token)
+		}
+	}
+
+	if len(stack) > 1 {
+		open := stack[len(stack)-1]
+		return nil, fmt.Errorf("invalid mustache template: line %d: unclosed section {{#%s}}", open.line, open.value)
+	}
+	return root.children, nil
+}
+
+// tokenizeMustache splits a template into text and tags. Section, close and
+// comment tags that stand alone on a line remove that line from the output
+func tokenizeMustache(src string) ([]*mustacheNode, error) {
+	var tokens []*mustacheNode
+	emitText := func(text string) {
+		if text != "" {
+			tokens = append(tokens, &mustacheNode{kind: mustacheText, value: text})
+		}
+	}
+
+	pos, textStart := 0, 0
+	for {
+		open := strings.Index(src[pos:], "{{")
+		if open < 0 {
+			break
+		}
+		start := pos + open
+		line := strings.Count(src[:start], "\n") + 1
+
+		opening, closing := "{{", "}}"
+		if strings.HasPrefix(src[start:], "{{{") {
+			opening, closing = "{{{", "}}}"
+		}
+		length := strings.Index(src[start+len(opening):], closing)
+		if length < 0 {
+			return nil, fmt.Errorf("invalid mustache template: line %d: unclosed tag", line)
+		}
+		inner := strings.TrimSpace(src[start+len(opening) : start+len(opening)+length])
+		end := start + len(opening) + length + len(closing)
+
+		token := &mustacheNode{kind: mustacheVariable, value: inner, line: line}
+		if opening == "{{" && inner != "" {
+			sigil := inner[0]
+			name := strings.TrimSpace(inner[1:])
+			switch sigil {
+			case '#':
+				token.kind, token.value = mustacheSection, name
+			case '^':
+				token.kind, token.value = mustacheInverted, name
+			case '/':
+				token.kind, token.value = mustacheClose, name
+			case '!':
+				token.kind, token.value = mustacheComment, ""
+			case '&':
+				token.value = name
+			case '>', '=':
+				return nil, fmt.Errorf("invalid mustache template: line %d: {{%c}} tags are not supported", line, sigil)
+			}
+		}
+		if token.kind != mustacheComment && token.value == "" {
+			return nil, fmt.Errorf("invalid mustache template: line %d: empty tag", line)
+		}
+
+		textEnd := start
+		if token.kind != mustacheVariable {
+			if lineStart, lineEnd, ok := standaloneLine(src, start, end); ok {
+				textEnd, end = lineStart, lineEnd
+			}
+		}
+
+		emitText(src[textStart:textEnd])
+		tokens = append(tokens, token)
+		pos, textStart = end, end
+	}
+	emitText(src[textStart:])
+
+	return tokens, nil
+}
+
+// standaloneLine reports whether src[start:end] is the only non-blank text on
+// its line and returns the range of the whole line including its newline
+func standaloneLine(src string, start, end int) (int, int, bool) {
+	lineStart := strings.LastIndex(src[:start], "\n") + 1
+	if strings.TrimSpace(src[lineStart:start]) != "" {
+		return 0, 0, false
+	}
+
+	lineEnd := len(src)
+	if i := strings.Index(src[end:], "\n"); i >= 0 {
+		lineEnd = end + i + 1
+	}
+	if strings.TrimSpace(src[end:lineEnd]) != "" {
+		return 0, 0, false
+	}
+	return lineStart, lineEnd, true
+}
@@ -0,0 +1,68 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMustacheEngine_Render(t *testing.T) {
+	engine := NewMustacheEngine()
+
+	tests := []struct {
+		name     string
+		content  string
+		values   map[string]any
+		expected string
+	}{
+		{"variable", "Hello {{NAME}}!", map[string]any{"NAME": "Ada"}, "Hello Ada!"},
+		{"missing variable", "Hello {{ NAME }}!", map[string]any{}, "Hello !"},
+		{"no escaping", "{{CODE}} {{{CODE}}} {{&CODE}}", map[string]any{"CODE": "a<b"}, "a<b a<b a<b"},
+		{"section true", "{{#SECURITY}}Check auth.{{/SECURITY}}", map[string]any{"SECURITY": "true"}, "Check auth."},
+		{"section false", "{{#SECURITY}}Check auth.{{/SECURITY}}", map[string]any{"SECURITY": "false"}, ""},
+		{"inverted", "{{^SECURITY}}Skip.{{/SECURITY}}", map[string]any{}, "Skip."},
+		{"list", "{{#FILES}}- {{.}}\n{{/FILES}}", map[string]any{"FILES": "a.go\nb.go"}, "- a.go\n- b.go\n"},
+		{"standalone tags", "Start\n  {{#X}}\nInside\n  {{/X}}\n{{! note }}\nEnd", map[string]any{"X": "yes"}, "Start\nInside\nEnd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := engine.Render(tt.content, tt.values)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Render() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMustacheEngine_Variables(t *testing.T) {
+	engine := NewMustacheEngine()
+
+	placeholders, err := engine.Variables("{{#FILES}}{{.}} by {{AUTHOR}}{{/FILES}}{{^FILES}}none{{/FILES}} {{AUTHOR}}")
+	if err != nil {
+		t.Fatalf("Variables() error = %v", err)
+	}
+	expected := []Placeholder{{Name: "FILES"}, {Name: "AUTHOR"}}
+	if !reflect.DeepEqual(placeholders, expected) {
+		t.Errorf("Variables() = %+v, want %+v", placeholders, expected)
+	}
+}
+
+func TestMustacheEngine_Errors(t *testing.T) {
+	engine := NewMustacheEngine()
+
+	for _, content := range []string{
+		"{{#A}}unclosed",
+		"{{#A}}x{{/B}}",
+		"{{/A}}",
+		"{{NAME",
+		"{{> partial}}",
+		"{{}}",
+	} {
+		if _, err := engine.Render(content, map[string]any{}); err == nil {
+			t.Errorf("Render(%q) expected error", content)
+		}
+	}
+}
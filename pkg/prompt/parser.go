@@ -1,14 +1,17 @@
 package prompt
 
 import (
-	"regexp"
+	"fmt"
+	"slices"
+	"sort"
 	"strings"
 )
 
 // Parser interface handles placeholder parsing and substitution
 type Parser interface {
 	ParsePlaceholders(content string) ([]Placeholder, error)
-	SubstitutePlaceholders(content string, values map[string]string) string
+	ResolveDefaults(placeholders []Placeholder) ([]Placeholder, error)
+	SubstitutePlaceholders(content string, values map[string]any) (string, error)
 }
 
 // Placeholder represents a placeholder in a prompt
@@ -16,76 +19,388 @@ type Placeholder struct {
 	Name         string
 	DefaultValue string
 	HasDefault   bool
+	Operators    []string // expansion operator of each occurrence, in order
+	Required     bool     // true if any occurrence uses ${VAR:?message} or ${VAR?message}
+	Source       string   // value source reference the default was computed from, e.g. "@cmd:git diff"
+	DependsOn    []string // placeholders referenced by the default, e.g. USER for ${REVIEWER:-${USER}}
 }
 
 // DefaultParser implements placeholder parsing
-type DefaultParser struct{}
+type DefaultParser struct {
+	Sources *SourceRegistry // value sources available to defaults as @name:arg, may be nil
+	Filters *FilterRegistry // filters available as ${VAR|name:arg}, may be nil
+}
 
-// NewDefaultParser creates a new DefaultParser
+// NewDefaultParser creates a new DefaultParser with the built-in filters
 func NewDefaultParser() *DefaultParser {
-	return &DefaultParser{}
+	return &DefaultParser{
+		Filters: NewDefaultFilterRegistry(),
+	}
 }
 
-// ParsePlaceholders parses placeholders from content using regex
-// Supports ${VAR} and ${VAR:-default} syntax
+// ForSource returns the parser for content from the level source. Content
+// from bundles is untrusted, so its @cmd defaults fail with ErrUntrustedCommand
+// even if running commands is allowed
+func (p *DefaultParser) ForSource(source string) *DefaultParser {
+	if source != BUNDLE_SOURCE || p.Sources == nil {
+		return p
+	}
+	untrusted := *p
+	untrusted.Sources = p.Sources.untrusted()
+	return &untrusted
+}
+
+// ParsePlaceholders parses placeholders from content
+// Supports the POSIX/bash parameter expansion operators, e.g. ${VAR}, ${VAR:-default},
+// ${VAR:?message}, ${VAR:+alternate}, ${#VAR}, ${VAR/pattern/replacement} and ${VAR^^}.
+// Defaults may reference other placeholders, as in ${TITLE:-Fix for ${ISSUE}}; such
+// defaults are kept unevaluated and listed in DependsOn. Filters such as ${CODE|fence:go}
+// do not change the reported name, and built-in variables such as ${git.branch} are not reported.
+// Malformed placeholders, such as the ${arr[@]} of a shell snippet, are not
+// placeholders and stay text; ParseTemplate reports them as Diagnostics. A pipe
+// not followed by filters of p.Filters is text, as in ${FORMAT:-json|yaml}.
+// Defaults that reference each other are reported as ErrPlaceholderCycle
 func (p *DefaultParser) ParsePlaceholders(content string) ([]Placeholder, error) {
-	// Regex pattern to match ${VAR} and ${VAR:-default}
-	re := regexp.MustCompile(`\$\{([^}:]+)(?::-([^}]*))?\}`)
-	matches := re.FindAllStringSubmatch(content, -1)
-	
+	tmpl := parseTemplate(content, p.Filters)
+
 	var placeholders []Placeholder
-	seen := make(map[string]bool)
-	
-	for _, match := range matches {
-		name := match[1]
-		if seen[name] {
-			continue // Skip duplicates
-		}
-		seen[name] = true
-		
-		placeholder := Placeholder{
-			Name: name,
-		}
-		
-		// Check if this is a ${VAR:-default} pattern
-		if len(match) > 2 && strings.Contains(match[0], ":-") {
+	index := make(map[string]int)
+
+	walkPlaceholders(tmpl, func(node *PlaceholderNode) {
+		exp := node.exp
+		// Built-in variables such as ${git.branch} are filled in automatically
+		if IsBuiltin(exp.Name) {
+			return
+		}
+
+		i, seen := index[exp.Name]
+		if !seen {
+			i = len(placeholders)
+			index[exp.Name] = i
+			placeholders = append(placeholders, Placeholder{Name: exp.Name})
+		}
+
+		placeholder := &placeholders[i]
+		placeholder.Operators = append(placeholder.Operators, exp.Op)
+		if exp.required() {
+			placeholder.Required = true
+		}
+		// The first occurrence that carries a default provides it
+		if exp.providesDefault() && !placeholder.HasDefault {
 			placeholder.HasDefault = true
-			placeholder.DefaultValue = match[2]
+			placeholder.DependsOn = referencedNames(exp.word)
+			if len(placeholder.DependsOn) > 0 {
+				placeholder.DefaultValue = exp.Word
+			} else {
+				placeholder.DefaultValue = renderLiteral(exp.word, exp.Word)
+			}
 		}
-		
-		placeholders = append(placeholders, placeholder)
+	})
+
+	if err := checkPlaceholderCycles(placeholders); err != nil {
+		return nil, err
 	}
-	
+
 	return placeholders, nil
 }
 
+// ResolveDefaults computes defaults that reference a value source, such as
+// ${DIFF:-@cmd:git diff --staged}. Each distinct reference is resolved once.
+// Defaults that depend on other placeholders are resolved at substitution time
+func (p *DefaultParser) ResolveDefaults(placeholders []Placeholder) ([]Placeholder, error) {
+	cache := newSourceCache(p.Sources)
+	resolved := make([]Placeholder, len(placeholders))
+
+	for i, placeholder := range placeholders {
+		resolved[i] = placeholder
+		if !placeholder.HasDefault || len(placeholder.DependsOn) > 0 {
+			continue
+		}
+
+		value, err := cache.resolve(placeholder.Name, placeholder.DefaultValue)
+		if err != nil {
+			return nil, err
+		}
+		if value != placeholder.DefaultValue {
+			resolved[i].Source = placeholder.DefaultValue
+			resolved[i].DefaultValue = value
+		}
+	}
+
+	return resolved, nil
+}
+
 // SubstitutePlaceholders replaces placeholders with provided values
-// Handles $$ escaping for literal $
-func (p *DefaultParser) SubstitutePlaceholders(content string, values map[string]string) string {
-	// First handle literal $$ -> $
-	result := strings.ReplaceAll(content, "$$", "\x00LITERAL_DOLLAR\x00")
-	
-	// Regex pattern to match ${VAR} and ${VAR:-default}
-	re := regexp.MustCompile(`\$\{([^}:]+)(?::-([^}]*))?\}`)
-	
-	result = re.ReplaceAllStringFunc(result, func(match string) string {
-		submatch := re.FindStringSubmatch(match)
-		name := submatch[1]
-		defaultValue := ""
-		if len(submatch) > 2 {
-			defaultValue = submatch[2]
-		}
-		
-		if value, exists := values[name]; exists {
-			return value
-		}
-		return defaultValue
+// Handles $$ escaping for literal $; ${VAR:=word} assignments apply to later placeholders.
+// A placeholder without a value uses the default declared by any of its occurrences.
+// Lists and maps are formatted with FormatValue
+func (p *DefaultParser) SubstitutePlaceholders(content string, values map[string]any) (string, error) {
+	tmpl := parseTemplate(content, p.Filters)
+	scope := newRenderScope(values, p.Sources, p.Filters)
+	walkPlaceholders(tmpl, func(node *PlaceholderNode) {
+		if _, declared := scope.defaults[node.Name]; !declared && node.exp.providesDefault() {
+			scope.defaults[node.Name] = node.exp
+		}
+	})
+
+	return scope.render(tmpl, content)
+}
+
+// SortPlaceholders orders placeholders by name, moving each placeholder after
+// the placeholders its default depends on
+func SortPlaceholders(placeholders []Placeholder) []Placeholder {
+	byName := make(map[string]Placeholder, len(placeholders))
+	names := make([]string, 0, len(placeholders))
+	for _, placeholder := range placeholders {
+		byName[placeholder.Name] = placeholder
+		names = append(names, placeholder.Name)
+	}
+	sort.Strings(names)
+
+	sorted := make([]Placeholder, 0, len(placeholders))
+	visited := make(map[string]bool, len(placeholders))
+	var visit func(name string)
+	visit = func(name string) {
+		placeholder, ok := byName[name]
+		if !ok || visited[name] {
+			return
+		}
+		visited[name] = true
+
+		dependencies := append([]string(nil), placeholder.DependsOn...)
+		sort.Strings(dependencies)
+		for _, dependency := range dependencies {
+			visit(dependency)
+		}
+		sorted = append(sorted, placeholder)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+
+	return sorted
+}
+
+// UnresolvedPlaceholders returns the names of the placeholders that have
+// neither a value in values nor a default, in order. Placeholders tested with
+// ${VAR:+word} or ${VAR+word} anywhere are optional and not returned, since
+// the prompt handles them being unset, e.g. ${NOTE:+Note: ${NOTE}}
+func UnresolvedPlaceholders(placeholders []Placeholder, values map[string]any) []string {
+	var names []string
+	for _, placeholder := range placeholders {
+		if _, ok := values[placeholder.Name]; ok || placeholder.HasDefault {
+			continue
+		}
+		if !slices.Contains(placeholder.Operators, OP_ALTERNATE) && !slices.Contains(placeholder.Operators, OP_ALTERNATE_SET) {
+			names = append(names, placeholder.Name)
+		}
+	}
+	return names
+}
+
+// walkPlaceholders calls fn for every placeholder in tmpl in document order,
+// including placeholders nested in defaults, patterns and replacements
+func walkPlaceholders(tmpl *Template, fn func(*PlaceholderNode)) {
+	if tmpl == nil {
+		return
+	}
+	for _, node := range tmpl.Nodes {
+		if placeholderNode, ok := node.(*PlaceholderNode); ok {
+			fn(placeholderNode)
+			walkPlaceholders(placeholderNode.exp.word, fn)
+			walkPlaceholders(placeholderNode.exp.replace, fn)
+		}
+	}
+}
+
+// referencedNames returns the distinct placeholder names used in tmpl
+func referencedNames(tmpl *Template) []string {
+	var names []string
+	seen := make(map[string]bool)
+	walkPlaceholders(tmpl, func(node *PlaceholderNode) {
+		if !seen[node.Name] {
+			seen[node.Name] = true
+			names = append(names, node.Name)
+		}
 	})
-	
-	// Restore literal dollars
-	result = strings.ReplaceAll(result, "\x00LITERAL_DOLLAR\x00", "$")
-	
-	return result
+	return names
+}
+
+// renderLiteral renders a template without placeholders, turning $$ into $ and \| into |
+func renderLiteral(tmpl *Template, raw string) string {
+	if tmpl == nil {
+		return unescapePipes(raw)
+	}
+	var buf strings.Builder
+	for _, node := range tmpl.Nodes {
+		if _, ok := node.(*EscapeNode); ok {
+			buf.WriteString("$")
+		} else {
+			buf.WriteString(node.Source())
+		}
+	}
+	return unescapePipes(buf.String())
+}
+
+// checkPlaceholderCycles reports defaults that depend on themselves, directly or indirectly
+func checkPlaceholderCycles(placeholders []Placeholder) error {
+	dependencies := make(map[string][]string, len(placeholders))
+	for _, placeholder := range placeholders {
+		dependencies[placeholder.Name] = placeholder.DependsOn
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(placeholders))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return cycleError(path, name)
+		case done:
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dependency := range dependencies[name] {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+
+	for _, placeholder := range placeholders {
+		if err := visit(placeholder.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cycleError describes the cycle that closes when name is reached again from path
+func cycleError(path []string, name string) error {
+	start := 0
+	for i, seen := range path {
+		if seen == name {
+			start = i
+			break
+		}
+	}
+	cycle := append(append([]string(nil), path[start:]...), name)
+	return fmt.Errorf("%w: %s", ErrPlaceholderCycle, strings.Join(cycle, " -> "))
+}
+
+// renderScope holds the state of a single substitution
+type renderScope struct {
+	values    map[string]string
+	defaults  map[string]expansion // first occurrence declaring a default, per placeholder
+	resolving []string             // placeholders whose defaults are being evaluated
+	sources   *sourceCache
+	filters   *FilterRegistry
+}
+
+// newRenderScope formats values into a copy so that ${VAR:=word} does not leak into the caller's map
+func newRenderScope(values map[string]any, sources *SourceRegistry, filters *FilterRegistry) *renderScope {
+	return &renderScope{
+		values:   FormatValues(values),
+		defaults: make(map[string]expansion),
+		sources:  newSourceCache(sources),
+		filters:  filters,
+	}
+}
+
+// lookup returns the value of a placeholder, falling back to its declared default
+func (s *renderScope) lookup(name string) (string, bool, error) {
+	if value, set := s.values[name]; set {
+		return value, true, nil
+	}
+	declared, ok := s.defaults[name]
+	if !ok {
+		return "", false, nil
+	}
+
+	value, err := s.resolveDefault(name, declared.word, declared.Word)
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// resolveDefault evaluates the default word of a placeholder, running its
+// value source if it references one. Only a literal word can reference a
+// source: text produced by nested expansions, e.g. from values, never runs one
+func (s *renderScope) resolveDefault(name string, word *Template, raw string) (string, error) {
+	for _, resolving := range s.resolving {
+		if resolving == name {
+			return "", cycleError(s.resolving, name)
+		}
+	}
+
+	s.resolving = append(s.resolving, name)
+	defer func() { s.resolving = s.resolving[:len(s.resolving)-1] }()
+
+	value, err := s.renderWord(word, raw)
+	if err != nil {
+		return "", err
+	}
+	if !isLiteral(word) {
+		return value, nil
+	}
+	return s.sources.resolve(name, value)
+}
+
+// isLiteral reports whether a default word has no placeholders; a nil word is its raw text
+func isLiteral(word *Template) bool {
+	if word == nil {
+		return true
+	}
+	for _, node := range word.Nodes {
+		if _, ok := node.(*PlaceholderNode); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// renderWord renders an operand such as a default, where \| stands for a literal pipe
+func (s *renderScope) renderWord(tmpl *Template, raw string) (string, error) {
+	value, err := s.render(tmpl, raw)
+	return unescapePipes(value), err
+}
+
+// render evaluates a nested template such as a default word. A nil template,
+// for expansions built without the template parser, renders as raw
+func (s *renderScope) render(tmpl *Template, raw string) (string, error) {
+	if tmpl == nil {
+		return raw, nil
+	}
+
+	var buf strings.Builder
+	for _, node := range tmpl.Nodes {
+		switch n := node.(type) {
+		case *EscapeNode:
+			buf.WriteString("$")
+		case *PlaceholderNode:
+			value, err := n.exp.evaluate(s)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(value)
+		default:
+			// Text, directives and malformed placeholders are kept verbatim
+			buf.WriteString(node.Source())
+		}
+	}
+	return buf.String(), nil
 }
 
 // FakeParser simulates parser behavior for testing
@@ -105,12 +420,17 @@ func (f *FakeParser) ParsePlaceholders(content string) ([]Placeholder, error) {
 	return f.Placeholders, nil
 }
 
+// ResolveDefaults returns the placeholders unchanged for testing
+func (f *FakeParser) ResolveDefaults(placeholders []Placeholder) ([]Placeholder, error) {
+	return placeholders, nil
+}
+
 // SubstitutePlaceholders performs simple string replacement for testing
-func (f *FakeParser) SubstitutePlaceholders(content string, values map[string]string) string {
+func (f *FakeParser) SubstitutePlaceholders(content string, values map[string]any) (string, error) {
 	result := content
-	for key, value := range values {
+	for key, value := range FormatValues(values) {
 		result = strings.ReplaceAll(result, "${"+key+"}", value)
 		result = strings.ReplaceAll(result, "${"+key+":-", value+"}")
 	}
-	return result
+	return result, nil
 }
@@ -1,7 +1,9 @@
 package prompt
 
 import (
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -22,43 +24,43 @@ func TestDefaultParser_ParsePlaceholders(t *testing.T) {
 			name:    "simple placeholder",
 			content: "Hello ${NAME}!",
 			expected: []Placeholder{
-				{Name: "NAME", DefaultValue: "", HasDefault: false},
+				{Name: "NAME", DefaultValue: "", HasDefault: false, Operators: []string{""}},
 			},
 		},
 		{
 			name:    "placeholder with default",
 			content: "Hello ${NAME:-World}!",
 			expected: []Placeholder{
-				{Name: "NAME", DefaultValue: "World", HasDefault: true},
+				{Name: "NAME", DefaultValue: "World", HasDefault: true, Operators: []string{":-"}},
 			},
 		},
 		{
 			name:    "multiple placeholders",
 			content: "Hello ${FIRST} ${LAST:-Doe}!",
 			expected: []Placeholder{
-				{Name: "FIRST", DefaultValue: "", HasDefault: false},
-				{Name: "LAST", DefaultValue: "Doe", HasDefault: true},
+				{Name: "FIRST", DefaultValue: "", HasDefault: false, Operators: []string{""}},
+				{Name: "LAST", DefaultValue: "Doe", HasDefault: true, Operators: []string{":-"}},
 			},
 		},
 		{
 			name:    "duplicate placeholders",
 			content: "Hello ${NAME} and ${NAME} again!",
 			expected: []Placeholder{
-				{Name: "NAME", DefaultValue: "", HasDefault: false},
+				{Name: "NAME", DefaultValue: "", HasDefault: false, Operators: []string{"", ""}},
 			},
 		},
 		{
 			name:    "empty default value",
 			content: "Hello ${NAME:-}!",
 			expected: []Placeholder{
-				{Name: "NAME", DefaultValue: "", HasDefault: true},
+				{Name: "NAME", DefaultValue: "", HasDefault: true, Operators: []string{":-"}},
 			},
 		},
 		{
 			name:    "complex default with spaces",
 			content: "Hello ${NAME:-John Doe}!",
 			expected: []Placeholder{
-				{Name: "NAME", DefaultValue: "John Doe", HasDefault: true},
+				{Name: "NAME", DefaultValue: "John Doe", HasDefault: true, Operators: []string{":-"}},
 			},
 		},
 		{
@@ -92,68 +94,89 @@ func TestDefaultParser_SubstitutePlaceholders(t *testing.T) {
 	tests := []struct {
 		name     string
 		content  string
-		values   map[string]string
+		values   map[string]any
 		expected string
 	}{
 		{
 			name:     "no placeholders",
 			content:  "Hello world!",
-			values:   map[string]string{},
+			values:   map[string]any{},
 			expected: "Hello world!",
 		},
 		{
 			name:    "simple substitution",
 			content: "Hello ${NAME}!",
-			values:  map[string]string{"NAME": "John"},
+			values:  map[string]any{"NAME": "John"},
 			expected: "Hello John!",
 		},
 		{
 			name:    "use default value",
 			content: "Hello ${NAME:-World}!",
-			values:  map[string]string{},
+			values:  map[string]any{},
 			expected: "Hello World!",
 		},
 		{
 			name:    "override default value",
 			content: "Hello ${NAME:-World}!",
-			values:  map[string]string{"NAME": "John"},
+			values:  map[string]any{"NAME": "John"},
 			expected: "Hello John!",
 		},
 		{
 			name:    "multiple substitutions",
 			content: "Hello ${FIRST} ${LAST:-Doe}!",
-			values:  map[string]string{"FIRST": "John"},
+			values:  map[string]any{"FIRST": "John"},
 			expected: "Hello John Doe!",
 		},
 		{
-			name:    "empty value overrides default",
+			name:    "empty value uses default with :-",
 			content: "Hello ${NAME:-World}!",
-			values:  map[string]string{"NAME": ""},
+			values:  map[string]any{"NAME": ""},
+			expected: "Hello World!",
+		},
+		{
+			name:     "empty value is kept with -",
+			content:  "Hello ${NAME-World}!",
+			values:   map[string]any{"NAME": ""},
 			expected: "Hello !",
 		},
+		{
+			name:     "empty value is assigned the default with :=",
+			content:  "${NAME:=World} and ${NAME}",
+			values:   map[string]any{"NAME": ""},
+			expected: "World and World",
+		},
+		{
+			name:     "empty value is kept with =",
+			content:  "[${NAME=World}]",
+			values:   map[string]any{"NAME": ""},
+			expected: "[]",
+		},
 		{
 			name:    "missing value no default",
 			content: "Hello ${NAME}!",
-			values:  map[string]string{},
+			values:  map[string]any{},
 			expected: "Hello !",
 		},
 		{
 			name:     "escaped dollar signs",
 			content:  "Price: $$100",
-			values:   map[string]string{},
+			values:   map[string]any{},
 			expected: "Price: $100",
 		},
 		{
 			name:    "mixed escaped and placeholders",
 			content: "Price: $$${AMOUNT:-50}",
-			values:  map[string]string{},
+			values:  map[string]any{},
 			expected: "Price: $50",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := parser.SubstitutePlaceholders(tt.content, tt.values)
+			result, err := parser.SubstitutePlaceholders(tt.content, tt.values)
+			if err != nil {
+				t.Fatalf("SubstitutePlaceholders() error = %v", err)
+			}
 			if result != tt.expected {
 				t.Errorf("SubstitutePlaceholders() = %q, want %q", result, tt.expected)
 			}
@@ -179,10 +202,218 @@ func TestFakeParser(t *testing.T) {
 
 	// Test SubstitutePlaceholders
 	content := "Hello ${NAME}!"
-	values := map[string]string{"NAME": "World"}
-	result := fakeParser.SubstitutePlaceholders(content, values)
+	values := map[string]any{"NAME": "World"}
+	result, err := fakeParser.SubstitutePlaceholders(content, values)
+	if err != nil {
+		t.Fatalf("FakeParser.SubstitutePlaceholders() error = %v", err)
+	}
 	expected := "Hello World!"
 	if result != expected {
 		t.Errorf("FakeParser.SubstitutePlaceholders() = %q, want %q", result, expected)
 	}
 }
+
+func TestDefaultParser_ExpansionOperators(t *testing.T) {
+	parser := NewDefaultParser()
+
+	tests := []struct {
+		name     string
+		content  string
+		values   map[string]any
+		expected string
+	}{
+		{"default unset only", "${NAME-World}", map[string]any{"NAME": ""}, ""},
+		{"assign and reuse", "${NAME:=World} and ${NAME}", map[string]any{}, "World and World"},
+		{"assign replaces empty", "${NAME:=World}", map[string]any{"NAME": ""}, "World"},
+		{"assign keeps empty without colon", "${NAME=World}", map[string]any{"NAME": ""}, ""},
+		{"required set", "${NAME:?name is required}", map[string]any{"NAME": "John"}, "John"},
+		{"alternate when set", "${NAME:+Hello }${NAME}", map[string]any{"NAME": "John"}, "Hello John"},
+		{"alternate when empty", "${NAME:+Hello }x", map[string]any{"NAME": ""}, "x"},
+		{"alternate without colon", "${NAME+set}", map[string]any{"NAME": ""}, "set"},
+		{"length", "${#NAME}", map[string]any{"NAME": "héllo"}, "5"},
+		{"remove shortest prefix", "${PATH#*/}", map[string]any{"PATH": "a/b/c"}, "b/c"},
+		{"remove longest prefix", "${PATH##*/}", map[string]any{"PATH": "a/b/c"}, "c"},
+		{"remove shortest suffix", "${FILE%.*}", map[string]any{"FILE": "a.tar.gz"}, "a.tar"},
+		{"remove longest suffix", "${FILE%%.*}", map[string]any{"FILE": "a.tar.gz"}, "a"},
+		{"remove prefix rune", "${X#?}", map[string]any{"X": "éa"}, "a"},
+		{"remove suffix rune", "${X%?}", map[string]any{"X": "aé"}, "a"},
+		{"remove longest prefix runes", "${X##*é}", map[string]any{"X": "éaéb"}, "b"},
+		{"replace first", "${TEXT/o/0}", map[string]any{"TEXT": "foo boo"}, "f0o boo"},
+		{"replace all", "${TEXT//o/0}", map[string]any{"TEXT": "foo boo"}, "f00 b00"},
+		{"replace prefix", "${TEXT/#foo/bar}", map[string]any{"TEXT": "foo foo"}, "bar foo"},
+		{"replace prefix no match", "${TEXT/#boo/bar}", map[string]any{"TEXT": "foo boo"}, "foo boo"},
+		{"replace suffix", "${TEXT/%foo/bar}", map[string]any{"TEXT": "foo foo"}, "foo bar"},
+		{"replace with glob", "${TEXT//[0-9]/#}", map[string]any{"TEXT": "a1b22"}, "a#b##"},
+		{"delete pattern", "${TEXT// /}", map[string]any{"TEXT": "a b c"}, "abc"},
+		{"replace runes", "${TEXT//?/x}", map[string]any{"TEXT": "héé"}, "xxx"},
+		{"replace non-ASCII pattern", "${TEXT//é/e}", map[string]any{"TEXT": "héé"}, "hee"},
+		{"upper first", "${NAME^}", map[string]any{"NAME": "john"}, "John"},
+		{"upper all", "${NAME^^}", map[string]any{"NAME": "john"}, "JOHN"},
+		{"lower first", "${NAME,}", map[string]any{"NAME": "JOHN"}, "jOHN"},
+		{"lower all", "${NAME,,}", map[string]any{"NAME": "JOHN"}, "john"},
+		{"substring offset", "${NAME:2}", map[string]any{"NAME": "abcdef"}, "cdef"},
+		{"substring offset and length", "${NAME:1:3}", map[string]any{"NAME": "abcdef"}, "bcd"},
+		{"substring negative offset", "${NAME: -2}", map[string]any{"NAME": "abcdef"}, "ef"},
+		{"substring negative length", "${NAME:1:-1}", map[string]any{"NAME": "abcdef"}, "bcde"},
+		{"invalid expression left verbatim", "${foo bar} ${NAME}", map[string]any{"NAME": "x"}, "${foo bar} x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.SubstitutePlaceholders(tt.content, tt.values)
+			if err != nil {
+				t.Fatalf("SubstitutePlaceholders() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("SubstitutePlaceholders() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDefaultParser_RequiredPlaceholder(t *testing.T) {
+	parser := NewDefaultParser()
+
+	for _, values := range []map[string]any{{}, {"CODE": ""}} {
+		_, err := parser.SubstitutePlaceholders("Review ${CODE:?paste the code to review}", values)
+		if !errors.Is(err, ErrRequiredPlaceholder) {
+			t.Fatalf("SubstitutePlaceholders() error = %v, want ErrRequiredPlaceholder", err)
+		}
+		if !strings.Contains(err.Error(), "CODE: paste the code to review") {
+			t.Errorf("error %q does not carry the custom message", err)
+		}
+	}
+
+	// ${VAR?message} only fails when the variable is unset
+	result, err := parser.SubstitutePlaceholders("[${CODE?missing}]", map[string]any{"CODE": ""})
+	if err != nil || result != "[]" {
+		t.Errorf("SubstitutePlaceholders() = %q, %v, want \"[]\", nil", result, err)
+	}
+}
+
+func TestDefaultParser_ParsePlaceholderOperators(t *testing.T) {
+	parser := NewDefaultParser()
+
+	placeholders, err := parser.ParsePlaceholders("${CODE:?required} ${CODE^^} ${LANG} ${LANG:=Go} ${#NAME}")
+	if err != nil {
+		t.Fatalf("ParsePlaceholders() error = %v", err)
+	}
+
+	expected := []Placeholder{
+		{Name: "CODE", Operators: []string{OP_REQUIRED, OP_UPPER_ALL}, Required: true},
+		{Name: "LANG", DefaultValue: "Go", HasDefault: true, Operators: []string{OP_NONE, OP_ASSIGN}},
+		{Name: "NAME", Operators: []string{OP_LENGTH}},
+	}
+	if !reflect.DeepEqual(placeholders, expected) {
+		t.Errorf("ParsePlaceholders() = %+v, want %+v", placeholders, expected)
+	}
+}
+
+func TestDefaultParser_NestedDefaults(t *testing.T) {
+	parser := NewDefaultParser()
+
+	tests := []struct {
+		name     string
+		content  string
+		values   map[string]any
+		expected string
+	}{
+		{"reference set", "${REVIEWER:-${USER}}", map[string]any{"USER": "ada"}, "ada"},
+		{"reference declared default", "${REVIEWER:-${USER}} and ${USER:-bob}", map[string]any{}, "bob and bob"},
+		{"text around reference", "${TITLE:-Fix for ${ISSUE}}", map[string]any{"ISSUE": "#42"}, "Fix for #42"},
+		{"explicit value wins", "${TITLE:-Fix for ${ISSUE}}", map[string]any{"TITLE": "Docs"}, "Docs"},
+		{"nested defaults", "${A:-${B:-${C:-deep}}}", map[string]any{}, "deep"},
+		{"nested pattern", "${FILE/${EXT}/md}", map[string]any{"FILE": "a.txt", "EXT": "txt"}, "a.md"},
+		{"escape in default", "${PRICE:-$$${AMOUNT}}", map[string]any{"AMOUNT": "5"}, "$5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.SubstitutePlaceholders(tt.content, tt.values)
+			if err != nil {
+				t.Fatalf("SubstitutePlaceholders() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("SubstitutePlaceholders() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDefaultParser_NestedDefaultDependencies(t *testing.T) {
+	parser := NewDefaultParser()
+
+	placeholders, err := parser.ParsePlaceholders("${TITLE:-Fix for ${ISSUE}} by ${REVIEWER:-${USER:-me}}")
+	if err != nil {
+		t.Fatalf("ParsePlaceholders() error = %v", err)
+	}
+
+	expected := []Placeholder{
+		{Name: "TITLE", DefaultValue: "Fix for ${ISSUE}", HasDefault: true, Operators: []string{OP_DEFAULT}, DependsOn: []string{"ISSUE"}},
+		{Name: "ISSUE", Operators: []string{OP_NONE}},
+		{Name: "REVIEWER", DefaultValue: "${USER:-me}", HasDefault: true, Operators: []string{OP_DEFAULT}, DependsOn: []string{"USER"}},
+		{Name: "USER", DefaultValue: "me", HasDefault: true, Operators: []string{OP_DEFAULT}},
+	}
+	if !reflect.DeepEqual(placeholders, expected) {
+		t.Errorf("ParsePlaceholders() = %+v, want %+v", placeholders, expected)
+	}
+
+	var names []string
+	for _, p := range SortPlaceholders(placeholders) {
+		names = append(names, p.Name)
+	}
+	if want := []string{"ISSUE", "USER", "REVIEWER", "TITLE"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("SortPlaceholders() = %v, want %v", names, want)
+	}
+}
+
+func TestDefaultParser_PlaceholderCycle(t *testing.T) {
+	parser := NewDefaultParser()
+
+	_, err := parser.ParsePlaceholders("${A:-${B}} ${B:-x ${C}} ${C:-${A}}")
+	if !errors.Is(err, ErrPlaceholderCycle) {
+		t.Fatalf("ParsePlaceholders() error = %v, want ErrPlaceholderCycle", err)
+	}
+	if !strings.Contains(err.Error(), "A -> B -> C -> A") {
+		t.Errorf("error %q does not describe the cycle", err)
+	}
+
+	_, err = parser.SubstitutePlaceholders("${A:-${B}} ${B:-${A}}", map[string]any{})
+	if !errors.Is(err, ErrPlaceholderCycle) {
+		t.Errorf("SubstitutePlaceholders() error = %v, want ErrPlaceholderCycle", err)
+	}
+
+	result, err := parser.SubstitutePlaceholders("${A:-${B}} ${B:-${A}}", map[string]any{"B": "set"})
+	if err != nil || result != "set set" {
+		t.Errorf("SubstitutePlaceholders() = %q, %v, want cycle broken by explicit value", result, err)
+	}
+}
+
+func TestUnresolvedPlaceholders(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		values  map[string]any
+		want    []string
+	}{
+		{"all set", "${A} ${B}", map[string]any{"A": "a", "B": ""}, nil},
+		{"missing values", "${A} ${B} ${A}", map[string]any{}, []string{"A", "B"}},
+		{"defaults", "${A:-a} ${B-b} ${C:=c}", map[string]any{}, nil},
+		{"required", "${A:?needed}", map[string]any{}, []string{"A"}},
+		{"alternate only", "${A:+yes}${B+no}", map[string]any{}, nil},
+		{"alternate and plain", "${A:+note: ${A}} ${B}", map[string]any{}, []string{"B"}},
+		{"builtins", "${git.branch} ${proompt.date}", map[string]any{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			placeholders, err := NewDefaultParser().ParsePlaceholders(tt.content)
+			if err != nil {
+				t.Fatalf("ParsePlaceholders() failed: %v", err)
+			}
+			if got := UnresolvedPlaceholders(placeholders, tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnresolvedPlaceholders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
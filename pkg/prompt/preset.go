@@ -0,0 +1,351 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrPresetNotFound is returned when no level has a preset of the given name
+	ErrPresetNotFound = errors.New("preset not found")
+	// ErrInvalidPresetName is returned for preset names that are empty or contain a colon, slash or space
+	ErrInvalidPresetName = errors.New("invalid preset name")
+)
+
+// PRESETS_EXTENSION is appended to a prompt name to get the file holding its presets
+const PRESETS_EXTENSION = ".presets.yaml"
+
+// Preset is a named set of placeholder values for a prompt
+type Preset struct {
+	Name   string
+	Prompt string // name of the prompt the values are for
+	Source string // level of the presets file
+	Path   string
+	Values map[string]any
+	// ShadowedBy is the source of the preset that overrides this one; set by List only
+	ShadowedBy string
+}
+
+// Presets keeps the presets of a prompt in a <prompt>.presets.yaml file at
+// any level of the hierarchy, mapping preset names to placeholder values.
+// Like prompts, a preset overrides presets of the same name at lower levels
+type Presets struct {
+	Filesystem filesystem.Filesystem
+	Resolver   LocationResolver
+}
+
+// NewPresets creates Presets stored in the prompt locations of resolver
+func NewPresets(fs filesystem.Filesystem, resolver LocationResolver) *Presets {
+	return &Presets{
+		Filesystem: fs,
+		Resolver:   resolver,
+	}
+}
+
+// Get returns the preset name of the prompt promptName from the highest level
+// that has it. name may be qualified with a level, like project:security
+func (p *Presets) Get(promptName, name string) (*Preset, error) {
+	source, bare := SplitQualifiedName(name)
+	locations, err := p.Resolver.GetPromptPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, location := range locations {
+		if source != "" && location.Type != source {
+			continue
+		}
+		presets, err := p.readPresets(location, promptName)
+		if err != nil {
+			return nil, err
+		}
+		for _, preset := range presets {
+			if preset.Name == bare {
+				return &preset, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s of %s", ErrPresetNotFound, name, promptName)
+}
+
+// List returns the presets of the prompt promptName, or of all prompts if it
+// is empty, in the order of the hierarchy. Overridden presets have ShadowedBy set
+func (p *Presets) List(promptName string) ([]Preset, error) {
+	locations, err := p.Resolver.GetPromptPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	var presets []Preset
+	seenPaths := make(map[string]bool)
+	for _, location := range locations {
+		absPath := absolutePath(location.Path)
+		if seenPaths[absPath] {
+			continue // e.g. the directory level is the project level at the project root
+		}
+		seenPaths[absPath] = true
+
+		names := []string{promptName}
+		if promptName == "" {
+			names = p.walk(location.Path, "")
+		}
+		for _, name := range names {
+			found, err := p.readPresets(location, name)
+			if err != nil {
+				return nil, err
+			}
+			presets = append(presets, found...)
+		}
+	}
+
+	visible := make(map[string]string) // prompt and preset name -> source it is listed from
+	for i, preset := range presets {
+		key := preset.Prompt + ":" + preset.Name
+		if source, seen := visible[key]; seen {
+			presets[i].ShadowedBy = source
+			continue
+		}
+		visible[key] = preset.Source
+	}
+	return presets, nil
+}
+
+// Save stores values as the preset name of the prompt promptName at the level
+// location, replacing a preset of the same name there. Other presets and
+// comments in the file are kept
+func (p *Presets) Save(promptName, name, location string, values map[string]any) (*Preset, error) {
+	if err := validatePresetName(name); err != nil {
+		return nil, err
+	}
+	if err := validateName(promptName); err != nil {
+		return nil, err
+	}
+	dir, err := p.locationPath(location)
+	if err != nil {
+		return nil, err
+	}
+	presetsPath := dir + "/" + promptName + PRESETS_EXTENSION
+
+	node := &yaml.Node{}
+	if err := node.Encode(values); err != nil {
+		return nil, err
+	}
+
+	if err := p.Filesystem.MkdirAll(path.Dir(presetsPath), 0755); err != nil {
+		return nil, err
+	}
+	err = p.update(presetsPath, func(root *yaml.Node) {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == name {
+				root.Content[i+1] = node
+				return
+			}
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+		root.Content = append(root.Content, key, node)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Preset{Name: name, Prompt: promptName, Source: location, Path: presetsPath, Values: values}, nil
+}
+
+// Delete removes the preset name of the prompt promptName from the highest
+// level that has it, or from the level name is qualified with. The presets
+// file is removed along with its last preset
+func (p *Presets) Delete(promptName, name string) (*Preset, error) {
+	preset, err := p.Get(promptName, name)
+	if err != nil {
+		return nil, err
+	}
+
+	empty := false
+	err = p.update(preset.Path, func(root *yaml.Node) {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == preset.Name {
+				root.Content = append(root.Content[:i], root.Content[i+2:]...)
+				break
+			}
+		}
+		empty = len(root.Content) == 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		if err := p.Filesystem.Remove(preset.Path); err != nil {
+			return nil, err
+		}
+	}
+	return preset, nil
+}
+
+// update applies change to the mapping of presets in the file presetsPath
+// while holding its lock and writes the result back
+func (p *Presets) update(presetsPath string, change func(root *yaml.Node)) error {
+	unlock, err := p.Filesystem.Lock(presetsPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	document, err := p.readFile(presetsPath)
+	if err != nil {
+		return err
+	}
+	change(document.Content[0])
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return p.Filesystem.WriteFile(presetsPath, buf.Bytes(), 0644)
+}
+
+// readPresets returns the presets of the prompt promptName at location, in file order
+func (p *Presets) readPresets(location PromptLocation, promptName string) ([]Preset, error) {
+	presetsPath := location.Path + "/" + promptName + PRESETS_EXTENSION
+	document, err := p.readFile(presetsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	root := document.Content[0]
+	presets := make([]Preset, 0, len(root.Content)/2)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		values, _ := decodeNode(root.Content[i+1]).(map[string]any)
+		if values == nil {
+			values = make(map[string]any)
+		}
+		presets = append(presets, Preset{
+			Name:   root.Content[i].Value,
+			Prompt: promptName,
+			Source: location.Type,
+			Path:   presetsPath,
+			Values: values,
+		})
+	}
+	return presets, nil
+}
+
+// readFile parses the presets file presetsPath into a document whose content
+// is a mapping of preset names to values; a missing file holds no presets
+func (p *Presets) readFile(presetsPath string) (*yaml.Node, error) {
+	empty := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+
+	data, err := p.Filesystem.ReadFile(presetsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	document := &yaml.Node{}
+	if err := yaml.Unmarshal(data, document); err != nil {
+		return nil, fmt.Errorf("invalid presets in %s: %w", presetsPath, err)
+	}
+	if len(document.Content) == 0 {
+		return empty, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid presets in %s: line %d: expected preset names", presetsPath, root.Line)
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		value := root.Content[i+1]
+		if value.Kind != yaml.MappingNode && value.ShortTag() != "!!null" {
+			return nil, fmt.Errorf("invalid presets in %s: line %d: expected NAME: value pairs for preset %s", presetsPath, value.Line, root.Content[i].Value)
+		}
+	}
+	return document, nil
+}
+
+// walk returns the names of the prompts with a presets file below dir.
+// Hidden directories are skipped
+func (p *Presets) walk(dir, prefix string) []string {
+	entries, err := p.Filesystem.ReadDir(dir)
+	if err != nil {
+		return nil // Skip directories that can't be read
+	}
+
+	var names []string
+	for _, entry := range entries {
+		switch {
+		case entry.IsDir():
+			if !strings.HasPrefix(entry.Name(), ".") {
+				names = append(names, p.walk(dir+"/"+entry.Name(), prefix+entry.Name()+"/")...)
+			}
+		case strings.HasSuffix(entry.Name(), PRESETS_EXTENSION):
+			names = append(names, prefix+strings.TrimSuffix(entry.Name(), PRESETS_EXTENSION))
+		}
+	}
+	return names
+}
+
+// locationPath returns the directory of the level location
+func (p *Presets) locationPath(location string) (string, error) {
+	locations, err := p.Resolver.GetPromptPaths()
+	if err != nil {
+		return "", err
+	}
+	for _, loc := range locations {
+		if loc.Type == location {
+			return loc.Path, nil
+		}
+	}
+	return "", ErrInvalidLocation
+}
+
+// MovePresets moves the presets file of the prompt file oldPath to go with the
+// prompt file newPath. It fails with ErrPromptExists if newPath already has presets
+func (m *DefaultManager) MovePresets(oldPath, newPath string) error {
+	from, to := presetsPath(oldPath), presetsPath(newPath)
+	if absolutePath(from) == absolutePath(to) {
+		return nil
+	}
+
+	unlock, err := m.Filesystem.Lock(from)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := m.Filesystem.ReadFile(from)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := m.create(to, string(data)); err != nil {
+		return err
+	}
+	return m.Filesystem.Remove(from)
+}
+
+// presetsPath returns the path of the presets file of the prompt file promptPath
+func presetsPath(promptPath string) string {
+	return removeExtension(promptPath) + PRESETS_EXTENSION
+}
+
+// validatePresetName checks that name can be used as a preset name
+func validatePresetName(name string) error {
+	if name == "" || strings.ContainsAny(name, ":/ \t\n") {
+		return fmt.Errorf("%w: %q", ErrInvalidPresetName, name)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package prompt
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+func TestPresets(t *testing.T) {
+	dir := t.TempDir()
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{
+		{Type: "project", Path: dir + "/project"},
+		{Type: "user", Path: dir + "/user"},
+	}
+	presets := NewPresets(filesystem.NewRealFilesystem("/"), resolver)
+
+	if _, err := presets.Save("review", "security", "user", map[string]any{"FOCUS": "user security"}); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if _, err := presets.Save("review", "performance", "user", map[string]any{"FOCUS": "performance", "FILES": []any{"a.go"}}); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if _, err := presets.Save("review", "security", "project", map[string]any{"FOCUS": "security", "VERSION": "1.10"}); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if _, err := presets.Save("git/commit", "short", "project", map[string]any{"STYLE": "short"}); err != nil {
+		t.Fatalf("Save() of a nested prompt failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		preset string
+		source string
+		values map[string]any
+		err    error
+	}{
+		{name: "highest level wins", preset: "security", source: "project", values: map[string]any{"FOCUS": "security", "VERSION": "1.10"}},
+		{name: "qualified", preset: "user:security", source: "user", values: map[string]any{"FOCUS": "user security"}},
+		{name: "lower level", preset: "performance", source: "user", values: map[string]any{"FOCUS": "performance", "FILES": []any{"a.go"}}},
+		{name: "qualified with the wrong level", preset: "project:performance", err: ErrPresetNotFound},
+		{name: "missing", preset: "style", err: ErrPresetNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preset, err := presets.Get("review", tt.preset)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Get(%q) error = %v, want %v", tt.preset, err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if preset.Source != tt.source || !reflect.DeepEqual(preset.Values, tt.values) {
+				t.Errorf("Get(%q) = %s %v, want %s %v", tt.preset, preset.Source, preset.Values, tt.source, tt.values)
+			}
+		})
+	}
+
+	all, err := presets.List("")
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	var listed []string
+	for _, preset := range all {
+		listed = append(listed, preset.Source+":"+preset.Prompt+"/"+preset.Name+"<"+preset.ShadowedBy)
+	}
+	expected := []string{"project:git/commit/short<", "project:review/security<", "user:review/security<project", "user:review/performance<"}
+	if !reflect.DeepEqual(listed, expected) {
+		t.Errorf("List() = %v, want %v", listed, expected)
+	}
+
+	deleted, err := presets.Delete("review", "security")
+	if err != nil || deleted.Source != "project" {
+		t.Fatalf("Delete() = %+v, %v; want the project preset", deleted, err)
+	}
+	if _, err := os.Stat(dir + "/project/review.presets.yaml"); !os.IsNotExist(err) {
+		t.Errorf("Expected the presets file without presets to be removed, got %v", err)
+	}
+	if preset, err := presets.Get("review", "security"); err != nil || preset.Source != "user" {
+		t.Errorf("Get() after Delete() = %+v, %v; want the user preset", preset, err)
+	}
+}
+
+func TestPresetsKeepComments(t *testing.T) {
+	dir := t.TempDir()
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{{Type: "project", Path: dir}}
+	presets := NewPresets(filesystem.NewRealFilesystem("/"), resolver)
+
+	content := "# Shared review presets\nsecurity: # for audits\n  FOCUS: security\n"
+	if err := os.WriteFile(dir+"/review.presets.yaml", []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := presets.Save("review", "performance", "project", map[string]any{"FOCUS": "performance"}); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(dir + "/review.presets.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := content + "performance:\n  FOCUS: performance\n"; string(data) != expected {
+		t.Errorf("Presets file = %q, want %q", data, expected)
+	}
+}
+
+func TestPresetsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{{Type: "user", Path: dir}}
+	presets := NewPresets(filesystem.NewRealFilesystem("/"), resolver)
+
+	for _, name := range []string{"", "user:security", "a b", "a/b"} {
+		if _, err := presets.Save("review", name, "user", map[string]any{"FOCUS": "x"}); !errors.Is(err, ErrInvalidPresetName) {
+			t.Errorf("Save() of preset %q error = %v, want ErrInvalidPresetName", name, err)
+		}
+	}
+	if _, err := presets.Save("review", "security", "project", map[string]any{"FOCUS": "x"}); !errors.Is(err, ErrInvalidLocation) {
+		t.Errorf("Save() at a missing level error = %v, want ErrInvalidLocation", err)
+	}
+
+	if err := os.WriteFile(dir+"/review.presets.yaml", []byte("security: focus\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := presets.Get("review", "security"); err == nil {
+		t.Error("Get() of a preset without a mapping of values succeeded, want an error")
+	}
+}
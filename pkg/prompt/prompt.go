@@ -2,6 +2,9 @@ package prompt
 
 import (
 	"errors"
+	"fmt"
+	"io/fs"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -14,29 +17,52 @@ var (
 	ErrPromptNotFound = errors.New("prompt not found")
 	// ErrInvalidLocation is returned when an invalid location is specified
 	ErrInvalidLocation = errors.New("invalid location")
+	// ErrInvalidName is returned when a prompt name is not a relative slash-separated path
+	ErrInvalidName = errors.New("invalid prompt name")
+	// ErrPromptChanged is returned by Update when a prompt file changed since it was read
+	ErrPromptChanged = errors.New("prompt changed on disk")
 )
 
 // Manager interface handles prompt management
 type Manager interface {
 	List() ([]PromptInfo, error)
+	ListAll() ([]PromptInfo, error)
+	Catalog() ([]PromptInfo, error)
 	Get(name string) (*PromptInfo, error)
+	GetAt(name, source string) (*PromptInfo, error)
+	GetBelow(name, source string) (*PromptInfo, error)
 	Create(name, content, location string) error
+	Store(name, content, location, ext string) (*PromptInfo, error)
+	PathAt(name, location, ext string) (string, error)
+	Update(info *PromptInfo, content string) (string, error)
 	Delete(name string) error
+	Remove(info *PromptInfo) error
+	MovePresets(oldPath, newPath string) error
+	Restore(name string) (*PromptInfo, error)
+	Snapshot(info *PromptInfo, action string) error
+	PlanRename(oldName, newName string) (*RenamePlan, error)
+	Rename(plan *RenamePlan) error
 	GetAllForPicker() ([]picker.PickerItem, error)
 }
 
 // PromptInfo contains information about a prompt
 type PromptInfo struct {
-	Name    string
-	Content string
-	Source  string
-	Path    string
+	Name     string
+	Content  string
+	Source   string
+	Path     string
+	Metadata Metadata // parsed from the frontmatter; empty if there is none or it is invalid
+	// ShadowedBy is the source of the prompt that overrides this one; set by ListAll only
+	ShadowedBy string
 }
 
 // DefaultManager implements prompt management
 type DefaultManager struct {
 	Filesystem filesystem.Filesystem
 	Resolver   LocationResolver
+	Trash      *Trash   // removed prompts are kept here if set
+	History    *History // snapshots of changed prompts are kept here if set
+	Index      *Index   // caches the metadata read by Catalog if set
 }
 
 // NewDefaultManager creates a new DefaultManager
@@ -49,84 +75,162 @@ func NewDefaultManager(fs filesystem.Filesystem, resolver LocationResolver) *Def
 
 // List returns all available prompts from all locations
 func (m *DefaultManager) List() ([]PromptInfo, error) {
-	locations, err := m.Resolver.GetPromptPaths()
+	all, err := m.listAll()
 	if err != nil {
 		return nil, err
 	}
 
 	var prompts []PromptInfo
-	seenPaths := make(map[string]bool) // Track absolute paths to avoid duplicates
-	seenNames := make(map[string]bool) // Track prompt names to respect hierarchy
-	
-	for _, location := range locations {
-		files, err := m.Filesystem.ReadDir(location.Path)
-		if err != nil {
-			continue // Skip locations that can't be read
+	for _, prompt := range all {
+		// Skip prompts overridden by a prompt of the same name (hierarchy respect)
+		if prompt.ShadowedBy == "" {
+			prompts = append(prompts, prompt)
 		}
+	}
+
+	return prompts, nil
+}
+
+// ListAll returns the prompts of all locations including shadowed ones, which
+// have ShadowedBy set to the source of the prompt that overrides them
+func (m *DefaultManager) ListAll() ([]PromptInfo, error) {
+	return m.listAll()
+}
 
-		for _, file := range files {
-			if !file.IsDir() && isPromptFile(file.Name()) {
-				fullPath := location.Path + "/" + file.Name()
-				
-				// Convert to absolute path for deduplication
-				absPath, err := filepath.Abs(fullPath)
-				if err != nil {
-					absPath = fullPath // Fallback to original path
-				}
-				
-				// Skip if we've already seen this file path
-				if seenPaths[absPath] {
-					continue
-				}
-				seenPaths[absPath] = true
-				
-				promptName := removeExtension(file.Name())
-				
-				// Skip if we've already seen this prompt name (hierarchy respect)
-				if seenNames[promptName] {
-					continue
-				}
-				seenNames[promptName] = true
-				
-				content, err := m.Filesystem.ReadFile(fullPath)
-				if err != nil {
-					continue // Skip files that can't be read
-				}
-
-				prompts = append(prompts, PromptInfo{
-					Name:    promptName,
-					Content: string(content),
-					Source:  location.Type,
-					Path:    fullPath,
-				})
-			}
+// listAll returns the prompts of all locations in priority order with their
+// content, including prompts shadowed by a prompt of the same name at a higher level
+func (m *DefaultManager) listAll() ([]PromptInfo, error) {
+	entries, _, err := m.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make([]bool, len(entries))
+	forEach(len(entries), func(i int) {
+		loaded[i] = m.load(&entries[i].PromptInfo) == nil
+	})
+
+	var prompts []PromptInfo
+	for i, entry := range entries {
+		if loaded[i] { // Skip files that can't be read
+			prompts = append(prompts, entry.PromptInfo)
 		}
 	}
 
-	return prompts, nil
+	return markShadowed(prompts), nil
 }
 
-// Get returns a specific prompt by name
+// Get returns a specific prompt by name. A source-qualified name such as
+// user:review returns the prompt of that level even if it is shadowed. Only
+// the file of the prompt found is read
 func (m *DefaultManager) Get(name string) (*PromptInfo, error) {
-	prompts, err := m.List()
+	entries, _, err := m.scan()
 	if err != nil {
 		return nil, err
 	}
 
-	for _, prompt := range prompts {
-		if prompt.Name == name {
-			return &prompt, nil
+	return m.loadFound(resolveEntry(entries, name))
+}
+
+// GetAt returns the prompt called name from the level source
+func (m *DefaultManager) GetAt(name, source string) (*PromptInfo, error) {
+	entries, _, err := m.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.loadFound(resolveEntry(entries, source+":"+name))
+}
+
+// GetBelow returns the highest-priority prompt called name from the levels
+// below source, i.e. the prompt that a prompt of that name at source shadows
+func (m *DefaultManager) GetBelow(name, source string) (*PromptInfo, error) {
+	entries, _, err := m.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	below := false
+	for i, entry := range entries {
+		if entry.Source == source {
+			below = true
+			continue
+		}
+		if below && entry.Name == name {
+			return m.loadFound(&entries[i])
 		}
 	}
 
 	return nil, ErrPromptNotFound
 }
 
+// resolveEntry finds a possibly source-qualified name in entries the way Get does
+func resolveEntry(entries []catalogEntry, name string) *catalogEntry {
+	source, bare := SplitQualifiedName(name)
+	for i, entry := range entries {
+		if entry.Name == bare && (source == "" || entry.Source == source) {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// loadFound reads the content of a prompt found by scan, or returns ErrPromptNotFound for nil
+func (m *DefaultManager) loadFound(entry *catalogEntry) (*PromptInfo, error) {
+	if entry == nil {
+		return nil, ErrPromptNotFound
+	}
+	prompt := entry.PromptInfo
+	if err := m.load(&prompt); err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
 // Create creates a new prompt at the specified location
 func (m *DefaultManager) Create(name, content, location string) error {
+	_, err := m.Store(name, content, location, ".md")
+	return err
+}
+
+// Store writes content as the prompt name at location, replacing the file if
+// it exists. ext is the file extension, such as .md or CHAT_EXTENSION
+func (m *DefaultManager) Store(name, content, location, ext string) (*PromptInfo, error) {
+	filepath, err := m.PathAt(name, location, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure the directory exists, including the directories of a nested name like git/commit
+	err = m.Filesystem.MkdirAll(path.Dir(filepath), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := m.Filesystem.Lock(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := m.Filesystem.WriteFile(filepath, []byte(content), 0644); err != nil {
+		return nil, err
+	}
+
+	return &PromptInfo{
+		Name:    name,
+		Content: content,
+		Source:  location,
+		Path:    filepath,
+	}, nil
+}
+
+// PathAt returns the path of the file Store writes for the prompt name with
+// the extension ext at location
+func (m *DefaultManager) PathAt(name, location, ext string) (string, error) {
 	locations, err := m.Resolver.GetPromptPaths()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	var targetPath string
@@ -138,44 +242,180 @@ func (m *DefaultManager) Create(name, content, location string) error {
 	}
 
 	if targetPath == "" {
-		return ErrInvalidLocation
+		return "", ErrInvalidLocation
+	}
+
+	if err := validateName(name); err != nil {
+		return "", err
 	}
 
-	// Ensure the directory exists
-	err = m.Filesystem.MkdirAll(targetPath, 0755)
+	return targetPath + "/" + name + ext, nil
+}
+
+// Update replaces the content of the prompt file info.Path if it still holds
+// info.Content. If another process changed the file in the meantime, nothing is
+// written and ErrPromptChanged is returned together with the file's current
+// content, or "" if it was removed
+func (m *DefaultManager) Update(info *PromptInfo, content string) (string, error) {
+	unlock, err := m.Filesystem.Lock(info.Path)
 	if err != nil {
-		return err
+		return "", err
 	}
+	defer unlock()
 
-	filename := name + ".md"
-	filepath := targetPath + "/" + filename
-	
-	return m.Filesystem.WriteFile(filepath, []byte(content), 0644)
+	current, err := m.Filesystem.ReadFile(info.Path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if string(current) != info.Content {
+		return string(current), ErrPromptChanged
+	}
+
+	if err := m.Filesystem.WriteFile(info.Path, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return content, nil
 }
 
-// Delete removes a prompt by name
+// Delete removes a prompt by name together with its presets, moving both to
+// the trash if there is one
 func (m *DefaultManager) Delete(name string) error {
 	prompt, err := m.Get(name)
 	if err != nil {
 		return err
 	}
+	return m.remove(prompt, true)
+}
+
+// Remove removes the prompt file of info, moving it to the trash if there is
+// one. Unlike Delete it keeps the presets, e.g. for a prompt that is replaced
+// by a file with another extension
+func (m *DefaultManager) Remove(info *PromptInfo) error {
+	return m.remove(info, false)
+}
+
+// remove removes the prompt file of prompt and, if withPresets is set, its presets file
+func (m *DefaultManager) remove(prompt *PromptInfo, withPresets bool) error {
+	unlock, err := m.Filesystem.Lock(prompt.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var presets []byte
+	presetsFile := presetsPath(prompt.Path)
+	if withPresets {
+		unlockPresets, err := m.Filesystem.Lock(presetsFile)
+		if err != nil {
+			return err
+		}
+		defer unlockPresets()
+
+		presets, err = m.Filesystem.ReadFile(presetsFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	if m.Trash != nil {
+		entry, err := m.Trash.Put(prompt, string(presets))
+		if err != nil {
+			return fmt.Errorf("failed to move prompt to trash: %w", err)
+		}
+		if err := m.Filesystem.Remove(prompt.Path); err != nil {
+			m.Trash.Remove(entry)
+			return err
+		}
+	} else if err := m.Filesystem.Remove(prompt.Path); err != nil {
+		return err
+	}
 
-	return m.Filesystem.Remove(prompt.Path)
+	if presets != nil {
+		return m.Filesystem.Remove(presetsFile)
+	}
+	return nil
+}
+
+// Restore puts the most recently removed prompt called name and its presets
+// back at its original absolute path. A source-qualified name restores a prompt removed from that level
+func (m *DefaultManager) Restore(name string) (*PromptInfo, error) {
+	if m.Trash == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotInTrash, name)
+	}
+
+	entry, err := m.Trash.Find(name)
+	if err != nil {
+		return nil, err
+	}
+	// Entries removed by older versions have paths relative to an unknown directory
+	if !filepath.IsAbs(entry.Path) {
+		return nil, fmt.Errorf("cannot restore %s: its original path %s is not absolute", name, entry.Path)
+	}
+
+	unlock, err := m.Filesystem.Lock(entry.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, err := m.Filesystem.Stat(entry.Path); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPromptExists, entry.Path)
+	}
+
+	if err := m.Filesystem.MkdirAll(path.Dir(entry.Path), 0755); err != nil {
+		return nil, err
+	}
+	if err := m.Filesystem.WriteFile(entry.Path, []byte(entry.Content), 0644); err != nil {
+		return nil, err
+	}
+	// Presets created since the prompt was removed are kept
+	if entry.Presets != "" {
+		if err := m.create(presetsPath(entry.Path), entry.Presets); err != nil && !errors.Is(err, ErrPromptExists) {
+			return nil, fmt.Errorf("restored %s but failed to restore its presets: %w", entry.Path, err)
+		}
+	}
+	if err := m.Trash.Remove(entry); err != nil {
+		return nil, fmt.Errorf("restored %s but failed to remove it from the trash: %w", entry.Path, err)
+	}
+
+	return &PromptInfo{
+		Name:    entry.Name,
+		Content: entry.Content,
+		Source:  entry.Source,
+		Path:    entry.Path,
+	}, nil
+}
+
+// Snapshot records the content of info in the history before action changes
+// the prompt. It does nothing if the manager has no history
+func (m *DefaultManager) Snapshot(info *PromptInfo, action string) error {
+	if m.History == nil {
+		return nil
+	}
+	if _, err := m.History.Record(info, action); err != nil {
+		return fmt.Errorf("failed to record history of %s: %w", info.Name, err)
+	}
+	return nil
 }
 
 // GetAllForPicker returns all prompts formatted for picker interface
 func (m *DefaultManager) GetAllForPicker() ([]picker.PickerItem, error) {
-	prompts, err := m.List()
+	prompts, err := m.Catalog()
 	if err != nil {
 		return nil, err
 	}
 
 	var items []picker.PickerItem
 	for _, prompt := range prompts {
+		if prompt.ShadowedBy != "" {
+			continue
+		}
 		items = append(items, picker.PickerItem{
-			Name:   prompt.Name,
-			Source: prompt.Source,
-			Path:   prompt.Path,
+			Name:        prompt.Name,
+			Source:      prompt.Source,
+			Path:        prompt.Path,
+			Description: prompt.Metadata.Description,
+			Tags:        prompt.Metadata.Tags,
 		})
 	}
 
@@ -185,10 +425,43 @@ func (m *DefaultManager) GetAllForPicker() ([]picker.PickerItem, error) {
 // isPromptFile checks if a file is a valid prompt file based on extension
 func isPromptFile(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
-	return ext == ".md" || ext == ".txt"
+	return ext == ".md" || ext == ".txt" || IsChatPrompt(filename)
+}
+
+// SplitQualifiedName splits a source-qualified name such as user:review or
+// project-local:git/commit into its source and prompt name. Unqualified names
+// are returned with an empty source
+func SplitQualifiedName(name string) (source, bare string) {
+	prefix, rest, found := strings.Cut(name, ":")
+	if found {
+		switch prefix {
+		case "directory", "project", "project-local", "user", BUNDLE_SOURCE:
+			return prefix, rest
+		}
+	}
+	return "", name
+}
+
+// validateName checks that name is made of slash-separated segments such as
+// git/commit, none of them empty, "." or ".."
+func validateName(name string) error {
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." || strings.Contains(segment, "\\") {
+			return fmt.Errorf("%w %q", ErrInvalidName, name)
+		}
+	}
+	return nil
+}
+
+// Extension returns the file extension of a prompt file, e.g. .md or .prompt.yaml
+func Extension(path string) string {
+	if IsChatPrompt(path) {
+		return path[len(path)-len(CHAT_EXTENSION):]
+	}
+	return filepath.Ext(path)
 }
 
 // removeExtension removes the file extension from a filename
 func removeExtension(filename string) string {
-	return strings.TrimSuffix(filename, filepath.Ext(filename))
+	return strings.TrimSuffix(filename, Extension(filename))
 }
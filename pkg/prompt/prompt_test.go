@@ -1,6 +1,8 @@
 package prompt
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"testing/fstest"
 
@@ -333,3 +335,286 @@ func TestRemoveExtension(t *testing.T) {
 		}
 	}
 }
+
+func TestDefaultManagerListMetadata(t *testing.T) {
+	fs := filesystem.NewFakeFilesystem()
+	fs.MapFS["prompts/review.md"] = &fstest.MapFile{
+		Data: []byte("---\ndescription: Review code\ntags: [go]\n---\nReview ${CODE}"),
+		Mode: 0644,
+	}
+	fs.MapFS["prompts/broken.md"] = &fstest.MapFile{
+		Data: []byte("---\ndescription: [unclosed\n---\nBody"),
+		Mode: 0644,
+	}
+
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{
+		{Type: "directory", Path: "prompts"},
+	}
+
+	manager := NewDefaultManager(fs, resolver)
+
+	review, err := manager.Get("review")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if review.Metadata.Description != "Review code" || len(review.Metadata.Tags) != 1 {
+		t.Errorf("Expected metadata to be parsed, got %+v", review.Metadata)
+	}
+
+	broken, err := manager.Get("broken")
+	if err != nil {
+		t.Fatalf("Get() should list prompts with invalid frontmatter: %v", err)
+	}
+	if broken.Metadata.Description != "" {
+		t.Errorf("Expected empty metadata for invalid frontmatter, got %+v", broken.Metadata)
+	}
+
+	items, err := manager.GetAllForPicker()
+	if err != nil {
+		t.Fatalf("GetAllForPicker() failed: %v", err)
+	}
+	for _, item := range items {
+		if item.Name == "review" && item.Description != "Review code" {
+			t.Errorf("Expected picker item to carry the description, got %+v", item)
+		}
+	}
+}
+
+func TestDefaultManagerListNested(t *testing.T) {
+	fs := filesystem.NewFakeFilesystem()
+	fs.MapFS["prompts/git/commit.md"] = &fstest.MapFile{Data: []byte("directory commit"), Mode: 0644}
+	fs.MapFS["prompts/git/hooks/pre-push.md"] = &fstest.MapFile{Data: []byte("pre-push"), Mode: 0644}
+	fs.MapFS["prompts/.hidden/secret.md"] = &fstest.MapFile{Data: []byte("hidden"), Mode: 0644}
+	fs.MapFS["user/prompts/git/commit.md"] = &fstest.MapFile{Data: []byte("user commit"), Mode: 0644}
+	fs.MapFS["user/prompts/git/review.md"] = &fstest.MapFile{Data: []byte("user review"), Mode: 0644}
+	fs.MapFS["user/prompts/commit.md"] = &fstest.MapFile{Data: []byte("top-level commit"), Mode: 0644}
+
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{
+		{Type: "directory", Path: "prompts"},
+		{Type: "user", Path: "user/prompts"},
+	}
+
+	manager := NewDefaultManager(fs, resolver)
+
+	prompts, err := manager.List()
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, prompt := range prompts {
+		got[prompt.Name] = prompt.Source + ":" + prompt.Content
+	}
+	expected := map[string]string{
+		"git/commit":         "directory:directory commit",
+		"git/hooks/pre-push": "directory:pre-push",
+		"git/review":         "user:user review",
+		"commit":             "user:top-level commit",
+	}
+	if len(got) != len(expected) {
+		t.Errorf("List() = %v, want %v", got, expected)
+	}
+	for name, want := range expected {
+		if got[name] != want {
+			t.Errorf("List() %s = %q, want %q", name, got[name], want)
+		}
+	}
+}
+
+func TestDefaultManagerCreateNested(t *testing.T) {
+	dir := t.TempDir()
+	fs := filesystem.NewRealFilesystem(dir)
+
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{
+		{Type: "project", Path: dir + "/prompts"},
+	}
+
+	manager := NewDefaultManager(fs, resolver)
+
+	if err := manager.Create("git/commit", "Commit message", "project"); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+
+	prompt, err := manager.Get("git/commit")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if prompt.Path != dir+"/prompts/git/commit.md" || prompt.Content != "Commit message" {
+		t.Errorf("Get() = %+v", prompt)
+	}
+
+	for _, name := range []string{"../escape", "git//commit", "/abs", "git/./commit", ""} {
+		if err := manager.Create(name, "", "project"); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Create(%q) error = %v, want ErrInvalidName", name, err)
+		}
+	}
+}
+
+func TestDefaultManagerStore(t *testing.T) {
+	dir := t.TempDir()
+	fs := filesystem.NewRealFilesystem(dir)
+
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{
+		{Type: "user", Path: dir + "/prompts"},
+	}
+
+	manager := NewDefaultManager(fs, resolver)
+
+	info, err := manager.Store("chat/review", "messages: []\n", "user", CHAT_EXTENSION)
+	if err != nil {
+		t.Fatalf("Store() failed: %v", err)
+	}
+	if info.Path != dir+"/prompts/chat/review.prompt.yaml" || info.Source != "user" || info.Name != "chat/review" {
+		t.Errorf("Store() = %+v", info)
+	}
+
+	if _, err := manager.Store("review", "", "project", ".md"); !errors.Is(err, ErrInvalidLocation) {
+		t.Errorf("Store() error = %v, want ErrInvalidLocation", err)
+	}
+}
+
+func TestDefaultManagerUpdate(t *testing.T) {
+	tests := []struct {
+		name        string
+		onDisk      *string
+		base        string
+		wantErr     error
+		wantCurrent string
+		wantContent string
+	}{
+		{
+			name:        "unchanged file",
+			onDisk:      ptr("Original"),
+			base:        "Original",
+			wantCurrent: "Edited",
+			wantContent: "Edited",
+		},
+		{
+			name:        "file changed on disk",
+			onDisk:      ptr("Changed elsewhere"),
+			base:        "Original",
+			wantErr:     ErrPromptChanged,
+			wantCurrent: "Changed elsewhere",
+			wantContent: "Changed elsewhere",
+		},
+		{
+			name:        "file removed on disk",
+			base:        "Original",
+			wantErr:     ErrPromptChanged,
+			wantCurrent: "",
+		},
+		{
+			name:        "new file",
+			base:        "",
+			wantCurrent: "Edited",
+			wantContent: "Edited",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := filesystem.NewFakeFilesystem()
+			if tt.onDisk != nil {
+				fs.MapFS["prompts/review.md"] = &fstest.MapFile{Data: []byte(*tt.onDisk), Mode: 0644}
+			}
+			manager := NewDefaultManager(fs, NewFakeLocationResolver())
+
+			info := &PromptInfo{Name: "review", Source: "user", Path: "prompts/review.md", Content: tt.base}
+			current, err := manager.Update(info, "Edited")
+			if err != tt.wantErr {
+				t.Fatalf("Update() error = %v, want %v", err, tt.wantErr)
+			}
+			if current != tt.wantCurrent {
+				t.Errorf("Update() = %q, want %q", current, tt.wantCurrent)
+			}
+
+			data, _ := fs.ReadFile("prompts/review.md")
+			if string(data) != tt.wantContent {
+				t.Errorf("file content = %q, want %q", data, tt.wantContent)
+			}
+		})
+	}
+}
+
+// ptr returns a pointer to s
+func ptr(s string) *string {
+	return &s
+}
+
+func TestDefaultManagerShadowedPrompts(t *testing.T) {
+	fs := filesystem.NewFakeFilesystem()
+	fs.MapFS["project/prompts/review.md"] = &fstest.MapFile{Data: []byte("project review"), Mode: 0644}
+	fs.MapFS["user/prompts/review.md"] = &fstest.MapFile{Data: []byte("user review"), Mode: 0644}
+	fs.MapFS["user/prompts/commit.md"] = &fstest.MapFile{Data: []byte("user commit"), Mode: 0644}
+
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{
+		{Type: "project", Path: "project/prompts"},
+		{Type: "user", Path: "user/prompts"},
+	}
+
+	manager := NewDefaultManager(fs, resolver)
+
+	all, err := manager.ListAll()
+	if err != nil {
+		t.Fatalf("ListAll() failed: %v", err)
+	}
+	var got []string
+	for _, prompt := range all {
+		got = append(got, prompt.Source+":"+prompt.Name+">"+prompt.ShadowedBy)
+	}
+	expected := "project:review>|user:commit>|user:review>project"
+	if strings.Join(got, "|") != expected {
+		t.Errorf("ListAll() = %v, want %s", got, expected)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		err     error
+	}{
+		{"review", "project review", nil},
+		{"user:review", "user review", nil},
+		{"project:review", "project review", nil},
+		{"project:commit", "", ErrPromptNotFound},
+	}
+	for _, tt := range tests {
+		prompt, err := manager.Get(tt.name)
+		if err != tt.err {
+			t.Errorf("Get(%q) error = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && prompt.Content != tt.content {
+			t.Errorf("Get(%q) content = %q, want %q", tt.name, prompt.Content, tt.content)
+		}
+	}
+
+	if err := manager.Delete("user:review"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := fs.ReadFile("project/prompts/review.md"); err != nil {
+		t.Errorf("Delete(user:review) removed the project prompt: %v", err)
+	}
+}
+
+func TestSplitQualifiedName(t *testing.T) {
+	tests := []struct {
+		name, source, bare string
+	}{
+		{"review", "", "review"},
+		{"user:review", "user", "review"},
+		{"project-local:git/commit", "project-local", "git/commit"},
+		{"team:review", "", "team:review"},
+	}
+
+	for _, tt := range tests {
+		source, bare := SplitQualifiedName(tt.name)
+		if source != tt.source || bare != tt.bare {
+			t.Errorf("SplitQualifiedName(%q) = %q, %q, want %q, %q", tt.name, source, bare, tt.source, tt.bare)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+// ErrPromptExists is returned when a prompt would replace another prompt of the same name and level
+var ErrPromptExists = errors.New("prompt already exists")
+
+var extendsLinePattern = regexp.MustCompile(`^(extends:[ \t]*)(["']?)([^"'#\s]+)(["']?)([ \t]*(?:#.*)?\r?)$`)
+
+// Rewrite is the new content of a prompt that refers to a renamed prompt
+type Rewrite struct {
+	Prompt  PromptInfo
+	Content string
+}
+
+// RenamePlan describes renaming a prompt and the references to it that change.
+// It is created by PlanRename and carried out by Rename
+type RenamePlan struct {
+	Prompt   PromptInfo // the prompt being renamed
+	NewName  string
+	NewPath  string
+	Rewrites []Rewrite    // prompts whose includes or extends: refer to Prompt
+	ReadOnly []PromptInfo // prompts from bundles that refer to Prompt; they cannot be updated
+}
+
+// PlanRename prepares renaming the prompt oldName, which may be
+// source-qualified, to newName at the same level. Every prompt of every
+// location is scanned for ${@include:...} directives and extends: fields that
+// resolve to the renamed prompt; the plan holds their rewritten content.
+// Bundles are read-only: their prompts cannot be renamed, and those referring
+// to the renamed prompt are listed in ReadOnly instead of being rewritten
+func (m *DefaultManager) PlanRename(oldName, newName string) (*RenamePlan, error) {
+	if err := validateName(newName); err != nil {
+		return nil, err
+	}
+
+	prompts, err := m.listAll()
+	if err != nil {
+		return nil, err
+	}
+
+	target := resolvePrompt(prompts, oldName)
+	if target == nil {
+		return nil, ErrPromptNotFound
+	}
+	if target.Name == newName {
+		return nil, fmt.Errorf("%w %q: it already has that name", ErrInvalidName, newName)
+	}
+	if target.Source == BUNDLE_SOURCE {
+		return nil, fmt.Errorf("%w: %s:%s is part of a bundle", filesystem.ErrReadOnly, target.Source, target.Name)
+	}
+
+	if resolvePrompt(prompts, target.Source+":"+newName) != nil {
+		return nil, fmt.Errorf("%w: %s:%s", ErrPromptExists, target.Source, newName)
+	}
+
+	// An unqualified reference to newName must still find the renamed prompt
+	qualify := false
+	for _, prompt := range prompts {
+		if prompt.Source == target.Source {
+			break
+		}
+		if prompt.Name == newName {
+			qualify = true
+		}
+	}
+	rename := func(ref string) string {
+		if source, _ := SplitQualifiedName(ref); source != "" || qualify {
+			return target.Source + ":" + newName
+		}
+		return newName
+	}
+
+	plan := &RenamePlan{
+		Prompt:  *target,
+		NewName: newName,
+		NewPath: strings.TrimSuffix(target.Path, target.Name+Extension(target.Path)) + newName + Extension(target.Path),
+	}
+	for _, prompt := range prompts {
+		content := rewriteIncludes(prompt.Content, func(ref string) (string, bool) {
+			resolved := resolvePrompt(prompts, ref)
+			return rename(ref), resolved != nil && resolved.Path == target.Path
+		})
+		content = rewriteExtends(content, func(ref string) (string, bool) {
+			resolved := resolvePrompt(prompts, ref)
+			if ref == prompt.Name {
+				resolved = resolvePromptBelow(prompts, prompt.Name, prompt.Source)
+			}
+			return rename(ref), resolved != nil && resolved.Path == target.Path
+		})
+		if content != prompt.Content && prompt.Source == BUNDLE_SOURCE {
+			plan.ReadOnly = append(plan.ReadOnly, prompt)
+		} else if content != prompt.Content {
+			plan.Rewrites = append(plan.Rewrites, Rewrite{Prompt: prompt, Content: content})
+		}
+	}
+
+	return plan, nil
+}
+
+// Rename carries out a plan created by PlanRename: it writes the rewritten
+// prompts and moves the prompt file, its presets and its history to the new
+// name. If a rewrite or the move fails, the prompts already rewritten are
+// restored, so nothing has changed. It fails with ErrPromptExists if a prompt
+// or presets file was created at the new name since the plan was made
+func (m *DefaultManager) Rename(plan *RenamePlan) (err error) {
+	content := plan.Prompt.Content
+	for _, rewrite := range plan.Rewrites {
+		if rewrite.Prompt.Path == plan.Prompt.Path {
+			content = rewrite.Content
+		}
+	}
+
+	if _, err := m.Filesystem.Stat(presetsPath(plan.NewPath)); err == nil {
+		return fmt.Errorf("%w: %s", ErrPromptExists, presetsPath(plan.NewPath))
+	}
+
+	// The references are rewritten first, so a prompt that cannot be written
+	// stops the rename before anything moved
+	var rewritten []Rewrite
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, rewrite := range rewritten {
+			m.Update(&PromptInfo{Path: rewrite.Prompt.Path, Content: rewrite.Content}, rewrite.Prompt.Content)
+		}
+	}()
+
+	// Prompts edited since the plan was made are not overwritten
+	for _, rewrite := range plan.Rewrites {
+		if rewrite.Prompt.Path == plan.Prompt.Path {
+			continue
+		}
+		if _, err := m.Update(&rewrite.Prompt, rewrite.Content); err != nil {
+			return fmt.Errorf("failed to update %s: %w", rewrite.Prompt.Path, err)
+		}
+		rewritten = append(rewritten, rewrite)
+	}
+
+	if err := m.create(plan.NewPath, content); err != nil {
+		return err
+	}
+	unlock, err := m.Filesystem.Lock(plan.Prompt.Path)
+	if err != nil {
+		m.Filesystem.Remove(plan.NewPath)
+		return err
+	}
+	err = m.Filesystem.Remove(plan.Prompt.Path)
+	unlock()
+	if err != nil {
+		m.Filesystem.Remove(plan.NewPath)
+		return err
+	}
+	rewritten = nil
+
+	if err := m.MovePresets(plan.Prompt.Path, plan.NewPath); err != nil {
+		return fmt.Errorf("failed to move the presets of %s: %w", plan.Prompt.Name, err)
+	}
+	if m.History != nil {
+		if err := m.History.Move(plan.Prompt.Path, plan.NewPath); err != nil {
+			return fmt.Errorf("failed to move the history of %s: %w", plan.Prompt.Name, err)
+		}
+	}
+	return nil
+}
+
+// create writes content to a new file at filePath, failing with ErrPromptExists if it exists
+func (m *DefaultManager) create(filePath, content string) error {
+	if err := m.Filesystem.MkdirAll(path.Dir(filePath), 0755); err != nil {
+		return err
+	}
+
+	unlock, err := m.Filesystem.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := m.Filesystem.Stat(filePath); err == nil {
+		return fmt.Errorf("%w: %s", ErrPromptExists, filePath)
+	}
+	return m.Filesystem.WriteFile(filePath, []byte(content), 0644)
+}
+
+// resolvePrompt finds name in prompts, listed in priority order, the way Get does
+func resolvePrompt(prompts []PromptInfo, name string) *PromptInfo {
+	source, bare := SplitQualifiedName(name)
+	for i, prompt := range prompts {
+		if prompt.Name == bare && (source == "" || prompt.Source == source) {
+			return &prompts[i]
+		}
+	}
+	return nil
+}
+
+// resolvePromptBelow finds name in the levels below source the way GetBelow does
+func resolvePromptBelow(prompts []PromptInfo, name, source string) *PromptInfo {
+	below := false
+	for i, prompt := range prompts {
+		if prompt.Source == source {
+			below = true
+			continue
+		}
+		if below && prompt.Name == name {
+			return &prompts[i]
+		}
+	}
+	return nil
+}
+
+// rewriteIncludes replaces the names of the ${@include:name} directives in
+// content for which rename reports true
+func rewriteIncludes(content string, rename func(ref string) (string, bool)) string {
+	var result strings.Builder
+	changed := false
+	for _, node := range ParseTemplate(content).Nodes {
+		source := node.Source()
+		if directive, ok := node.(*DirectiveNode); ok && directive.Name == "include" {
+			if newRef, ok := rename(directive.Arg); ok {
+				i := strings.LastIndex(source, directive.Arg)
+				source = source[:i] + newRef + source[i+len(directive.Arg):]
+				changed = true
+			}
+		}
+		result.WriteString(source)
+	}
+
+	if !changed {
+		return content
+	}
+	return result.String()
+}
+
+// rewriteExtends replaces the extends: field of the frontmatter in content if rename reports true for it
+func rewriteExtends(content string, rename func(ref string) (string, bool)) string {
+	if !strings.HasPrefix(content, "---\n") && !strings.HasPrefix(content, "---\r\n") {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], "\r") == "---" {
+			break
+		}
+		match := extendsLinePattern.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+		if newRef, ok := rename(match[3]); ok {
+			lines[i] = match[1] + match[2] + newRef + match[4] + match[5]
+			return strings.Join(lines, "\n")
+		}
+		break
+	}
+	return content
+}
@@ -0,0 +1,279 @@
+package prompt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+// newRenameManager creates a manager over the project and user levels of a temporary directory holding files
+func newRenameManager(t *testing.T, files map[string]string) (*DefaultManager, string) {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{
+		{Type: "project", Path: dir + "/project"},
+		{Type: "user", Path: dir + "/user"},
+	}
+	return NewDefaultManager(filesystem.NewRealFilesystem(dir), resolver), dir
+}
+
+func TestDefaultManagerPlanRename(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		old, new string
+		expected map[string]string // path relative to the temporary directory -> rewritten content
+		err      error
+	}{
+		{
+			name: "includes and extends",
+			files: map[string]string{
+				"user/conventions.md": "Be brief.",
+				"user/review.md":      "---\nextends: conventions # base rules\n---\n${@include:conventions}\n${@include: user:conventions }",
+				"project/other.md":    "No references to conventions here.",
+			},
+			old: "conventions", new: "style/conventions",
+			expected: map[string]string{
+				"user/review.md": "---\nextends: style/conventions # base rules\n---\n${@include:style/conventions}\n${@include: user:style/conventions }",
+			},
+		},
+		{
+			name: "shadowed prompt keeps unrelated references",
+			files: map[string]string{
+				"project/review.md": "---\nextends: review\n---\n# Extra",
+				"user/review.md":    "# Review",
+				"user/pick.md":      "${@include:review} ${@include:user:review}",
+			},
+			old: "user:review", new: "base-review",
+			expected: map[string]string{
+				"project/review.md": "---\nextends: base-review\n---\n# Extra",
+				"user/pick.md":      "${@include:review} ${@include:user:base-review}",
+			},
+		},
+		{
+			name: "references are qualified when a higher level has the new name",
+			files: map[string]string{
+				"project/base.md": "Project base",
+				"user/review.md":  "# Review",
+				"user/pick.md":    "${@include:review}",
+			},
+			old: "review", new: "base",
+			expected: map[string]string{
+				"user/pick.md": "${@include:user:base}",
+			},
+		},
+		{
+			name: "new name exists at the same level",
+			files: map[string]string{
+				"user/review.md": "# Review",
+				"user/base.md":   "# Base",
+			},
+			old: "review", new: "base",
+			err: ErrPromptExists,
+		},
+		{
+			name: "new name exists at the same level after other prompts",
+			files: map[string]string{
+				"project/base.md": "# Project base",
+				"user/a.md":       "# A",
+				"user/base.md":    "# Base",
+				"user/review.md":  "# Review",
+			},
+			old: "review", new: "base",
+			err: ErrPromptExists,
+		},
+		{
+			name:  "missing prompt",
+			files: map[string]string{},
+			old:   "review", new: "base",
+			err: ErrPromptNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, dir := newRenameManager(t, tt.files)
+
+			plan, err := manager.PlanRename(tt.old, tt.new)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("PlanRename() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PlanRename() failed: %v", err)
+			}
+
+			rewrites := make(map[string]string)
+			for _, rewrite := range plan.Rewrites {
+				rel, _ := filepath.Rel(dir, rewrite.Prompt.Path)
+				rewrites[rel] = rewrite.Content
+			}
+			if len(rewrites) != len(tt.expected) {
+				t.Errorf("PlanRename() rewrites = %q, want %q", rewrites, tt.expected)
+			}
+			for path, content := range tt.expected {
+				if rewrites[path] != content {
+					t.Errorf("rewrite of %s = %q, want %q", path, rewrites[path], content)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultManagerRename(t *testing.T) {
+	manager, dir := newRenameManager(t, map[string]string{
+		"user/review.prompt.yaml":  "messages:\n  - role: user\n    content: Review\n",
+		"user/review.presets.yaml": "short:\n  LENGTH: short\n",
+		"user/pick.md":             "${@include:review}",
+	})
+
+	manager.History = &History{Filesystem: manager.Filesystem, Resolver: manager.Resolver, Dir: dir + "/history", Now: time.Now}
+	old, _ := manager.Get("review")
+	if err := manager.Snapshot(old, "rename"); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := manager.PlanRename("review", "code/review")
+	if err != nil {
+		t.Fatalf("PlanRename() failed: %v", err)
+	}
+	if plan.NewPath != dir+"/user/code/review.prompt.yaml" {
+		t.Errorf("NewPath = %q", plan.NewPath)
+	}
+	if err := manager.Rename(plan); err != nil {
+		t.Fatalf("Rename() failed: %v", err)
+	}
+
+	if _, err := os.Stat(dir + "/user/review.prompt.yaml"); !os.IsNotExist(err) {
+		t.Errorf("Expected the old file to be removed, got %v", err)
+	}
+	renamed, err := manager.Get("code/review")
+	if err != nil || renamed.Content != "messages:\n  - role: user\n    content: Review\n" {
+		t.Errorf("Get() = %+v, %v", renamed, err)
+	}
+	pick, _ := os.ReadFile(dir + "/user/pick.md")
+	if string(pick) != "${@include:code/review}" {
+		t.Errorf("Expected the include to be rewritten, got %q", pick)
+	}
+	if presets, _ := os.ReadFile(dir + "/user/code/review.presets.yaml"); string(presets) != "short:\n  LENGTH: short\n" {
+		t.Errorf("Expected the presets to move with the prompt, got %q", presets)
+	}
+	if _, err := os.Stat(dir + "/user/review.presets.yaml"); !os.IsNotExist(err) {
+		t.Errorf("Expected the old presets file to be removed, got %v", err)
+	}
+	if revisions, _ := manager.History.Log("code/review"); len(revisions) != 1 || revisions[0].Action != "rename" {
+		t.Errorf("Log() of the new name = %+v, want the rename snapshot", revisions)
+	}
+	if revisions, _ := manager.History.Log("review"); len(revisions) != 0 {
+		t.Errorf("Log() of the old name = %+v, want no revisions", revisions)
+	}
+}
+
+func TestDefaultManagerRenameRefusesExistingFile(t *testing.T) {
+	manager, dir := newRenameManager(t, map[string]string{
+		"user/review.md": "# Review",
+	})
+
+	plan, err := manager.PlanRename("review", "base")
+	if err != nil {
+		t.Fatalf("PlanRename() failed: %v", err)
+	}
+	if err := os.WriteFile(plan.NewPath, []byte("# Base"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Rename(plan); !errors.Is(err, ErrPromptExists) {
+		t.Fatalf("Rename() error = %v, want ErrPromptExists", err)
+	}
+	if base, _ := os.ReadFile(dir + "/user/base.md"); string(base) != "# Base" {
+		t.Errorf("Expected the existing file to be kept, got %q", base)
+	}
+	if _, err := os.Stat(dir + "/user/review.md"); err != nil {
+		t.Errorf("Expected the prompt to be kept: %v", err)
+	}
+}
+
+func TestDefaultManagerRenameLeavesBundlesAlone(t *testing.T) {
+	manager, dir := newRenameManager(t, map[string]string{
+		"user/conventions.md": "Be brief.",
+		"user/review.md":      "${@include:conventions}",
+	})
+	mounted := filesystem.NewMountFS(manager.Filesystem)
+	mounted.Mount(dir+"/bundle", fstest.MapFS{
+		"team.md": &fstest.MapFile{Data: []byte("Team ${@include:conventions}")},
+	})
+	manager.Filesystem = mounted
+	resolver := manager.Resolver.(*FakeLocationResolver)
+	resolver.Locations = append(resolver.Locations, PromptLocation{Type: BUNDLE_SOURCE, Path: dir + "/bundle"})
+
+	if _, err := manager.PlanRename("team", "crew"); !errors.Is(err, filesystem.ErrReadOnly) {
+		t.Errorf("PlanRename() of a bundle prompt error = %v, want ErrReadOnly", err)
+	}
+
+	plan, err := manager.PlanRename("conventions", "style")
+	if err != nil {
+		t.Fatalf("PlanRename() failed: %v", err)
+	}
+	if len(plan.Rewrites) != 1 || plan.Rewrites[0].Prompt.Name != "review" {
+		t.Errorf("PlanRename() rewrites = %+v, want only review", plan.Rewrites)
+	}
+	if len(plan.ReadOnly) != 1 || plan.ReadOnly[0].Name != "team" {
+		t.Errorf("PlanRename() read-only = %+v, want team", plan.ReadOnly)
+	}
+
+	if err := manager.Rename(plan); err != nil {
+		t.Fatalf("Rename() failed: %v", err)
+	}
+	if review, _ := os.ReadFile(dir + "/user/review.md"); string(review) != "${@include:style}" {
+		t.Errorf("Expected the include to be rewritten, got %q", review)
+	}
+	if team, _ := manager.Get("team"); team == nil || team.Content != "Team ${@include:conventions}" {
+		t.Errorf("Expected the bundle prompt to be unchanged, got %+v", team)
+	}
+}
+
+func TestDefaultManagerRenameRestoresRewritesOnFailure(t *testing.T) {
+	manager, dir := newRenameManager(t, map[string]string{
+		"project/a.md":        "${@include:conventions}",
+		"user/b.md":           "${@include:conventions}",
+		"user/conventions.md": "Be brief.",
+	})
+
+	plan, err := manager.PlanRename("conventions", "style")
+	if err != nil {
+		t.Fatalf("PlanRename() failed: %v", err)
+	}
+	if err := os.WriteFile(dir+"/user/b.md", []byte("edited ${@include:conventions}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Rename(plan); !errors.Is(err, ErrPromptChanged) {
+		t.Fatalf("Rename() error = %v, want ErrPromptChanged", err)
+	}
+	if a, _ := os.ReadFile(dir + "/project/a.md"); string(a) != "${@include:conventions}" {
+		t.Errorf("Expected the rewrite of a to be restored, got %q", a)
+	}
+	if _, err := os.Stat(dir + "/user/conventions.md"); err != nil {
+		t.Errorf("Expected the prompt not to move: %v", err)
+	}
+	if _, err := os.Stat(dir + "/user/style.md"); !os.IsNotExist(err) {
+		t.Errorf("Expected no file at the new name, got %v", err)
+	}
+}
@@ -14,13 +14,16 @@ type LocationResolver interface {
 
 // PromptLocation represents a location where prompts can be found
 type PromptLocation struct {
-	Type string // "directory", "project", "project-local", "user"
+	Type string // "directory", "project", "project-local", "user", "bundle"
 	Path string
 }
 
 // DefaultLocationResolver implements the four-level prompt hierarchy
 type DefaultLocationResolver struct {
 	Filesystem filesystem.Filesystem
+	// Bundles are the paths of read-only bundles, mounted into Filesystem,
+	// that are searched after the user level
+	Bundles []string
 }
 
 // NewDefaultLocationResolver creates a new DefaultLocationResolver
@@ -42,8 +45,11 @@ func (r *DefaultLocationResolver) GetPromptPaths() ([]PromptLocation, error) {
 		})
 	}
 
+	// The project and project-local levels share the project root
+	projectPath, projectErr := r.findProjectRoot()
+
 	// 2. Project level: Find .git or prompts/ folder upward, use prompts/ subdirectory
-	if projectPath, err := r.findProjectRoot(); err == nil {
+	if projectErr == nil {
 		promptsPath := filepath.Join(projectPath, "prompts")
 		if info, err := r.Filesystem.Stat(promptsPath); err == nil && info.IsDir() {
 			locations = append(locations, PromptLocation{
@@ -54,7 +60,7 @@ func (r *DefaultLocationResolver) GetPromptPaths() ([]PromptLocation, error) {
 	}
 
 	// 3. Project-local level: Same as project but in .git/info/prompts/
-	if projectPath, err := r.findProjectRoot(); err == nil {
+	if projectErr == nil {
 		gitInfoPrompts := filepath.Join(projectPath, ".git", "info", "prompts")
 		if info, err := r.Filesystem.Stat(gitInfoPrompts); err == nil && info.IsDir() {
 			locations = append(locations, PromptLocation{
@@ -73,28 +79,51 @@ func (r *DefaultLocationResolver) GetPromptPaths() ([]PromptLocation, error) {
 		})
 	}
 
+	// 5. Bundles, in the order they were configured
+	for _, bundle := range r.Bundles {
+		if isDir(r.Filesystem, bundle) {
+			locations = append(locations, PromptLocation{
+				Type: BUNDLE_SOURCE,
+				Path: bundle,
+			})
+		}
+	}
+
 	return locations, nil
 }
 
 // findProjectRoot searches upward for .git directory or prompts/ folder
 func (r *DefaultLocationResolver) findProjectRoot() (string, error) {
-	cwd, err := r.Filesystem.Getwd()
+	return FindProjectRoot(r.Filesystem)
+}
+
+// FindProjectRoot searches upward from the working directory for a .git
+// directory or prompts/ folder and returns the directory containing it
+func FindProjectRoot(fs filesystem.Filesystem) (string, error) {
+	root, ok, err := findUpward(fs, func(dir string) bool {
+		return isDir(fs, filepath.Join(dir, ".git")) || isDir(fs, filepath.Join(dir, "prompts"))
+	})
 	if err != nil {
 		return "", err
 	}
+	if !ok {
+		return "", errors.New("project root not found")
+	}
+	return root, nil
+}
+
+// findUpward returns the first directory, starting at the working directory
+// and moving towards the root, for which found returns true
+func findUpward(fs filesystem.Filesystem, found func(dir string) bool) (string, bool, error) {
+	cwd, err := fs.Getwd()
+	if err != nil {
+		return "", false, err
+	}
 
 	current := cwd
 	for {
-		// Check for .git directory
-		gitPath := filepath.Join(current, ".git")
-		if info, err := r.Filesystem.Stat(gitPath); err == nil && info.IsDir() {
-			return current, nil
-		}
-
-		// Check for prompts directory
-		promptsPath := filepath.Join(current, "prompts")
-		if info, err := r.Filesystem.Stat(promptsPath); err == nil && info.IsDir() {
-			return current, nil
+		if found(current) {
+			return current, true, nil
 		}
 
 		// Move up one directory
@@ -106,7 +135,13 @@ func (r *DefaultLocationResolver) findProjectRoot() (string, error) {
 		current = parent
 	}
 
-	return "", errors.New("project root not found")
+	return "", false, nil
+}
+
+// isDir reports whether path exists and is a directory
+func isDir(fs filesystem.Filesystem, path string) bool {
+	info, err := fs.Stat(path)
+	return err == nil && info.IsDir()
 }
 
 // FakeLocationResolver simulates location resolution for testing
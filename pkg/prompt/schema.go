@@ -0,0 +1,208 @@
+package prompt
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Variable types that can be declared in a prompt's variables: block
+const (
+	TYPE_STRING    = "string"
+	TYPE_INT       = "int"
+	TYPE_BOOL      = "bool"
+	TYPE_ENUM      = "enum"
+	TYPE_MULTILINE = "multiline"
+	TYPE_PATH      = "path"
+	TYPE_LIST      = "list" // YAML sequence, e.g. [a.go, b.go]
+	TYPE_MAP       = "map"  // YAML mapping
+)
+
+// Schema declares the expected type of each placeholder of a prompt
+type Schema map[string]VariableSpec
+
+// VariableSpec describes a single placeholder
+type VariableSpec struct {
+	Type        string   `yaml:"type"`
+	Description string   `yaml:"description"`
+	Required    bool     `yaml:"required"`
+	Pattern     string   `yaml:"pattern"` // regular expression the whole value must match
+	Values      []string `yaml:"values"`  // allowed values for enum variables
+}
+
+// Violation is a single value that does not match its VariableSpec
+type Violation struct {
+	Name    string
+	Message string
+}
+
+// ValidationError reports every violation found when validating values
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Error implements error
+func (e *ValidationError) Error() string {
+	var buf strings.Builder
+	buf.WriteString("invalid placeholder values:")
+	for _, v := range e.Violations {
+		fmt.Fprintf(&buf, "\n  - %s: %s", v.Name, v.Message)
+	}
+	return buf.String()
+}
+
+// Check verifies that the schema itself is well-formed
+func (s Schema) Check() error {
+	for _, name := range s.names() {
+		spec := s[name]
+		switch spec.Type {
+		case "", TYPE_STRING, TYPE_INT, TYPE_BOOL, TYPE_MULTILINE, TYPE_PATH, TYPE_LIST, TYPE_MAP:
+		case TYPE_ENUM:
+			if len(spec.Values) == 0 {
+				return fmt.Errorf("variable %s: enum type requires a values list", name)
+			}
+		default:
+			return fmt.Errorf("variable %s: unknown type %q", name, spec.Type)
+		}
+
+		if spec.Pattern != "" {
+			if _, err := regexp.Compile(spec.Pattern); err != nil {
+				return fmt.Errorf("variable %s: invalid pattern: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks values against the schema and reports all violations at once.
+// Empty optional values are accepted for every type
+func (s Schema) Validate(values map[string]any) error {
+	var violations []Violation
+
+	for _, name := range s.names() {
+		spec := s[name]
+		value := values[name]
+
+		if isEmptyValue(value) {
+			if spec.Required {
+				violations = append(violations, Violation{Name: name, Message: "value is required"})
+			}
+			continue
+		}
+
+		if message := spec.check(value); message != "" {
+			violations = append(violations, Violation{Name: name, Message: message})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// check returns a description of what is wrong with value, or "" if it is valid
+func (v VariableSpec) check(structured any) string {
+	switch structured.(type) {
+	case []any:
+		if v.Type != TYPE_LIST {
+			return fmt.Sprintf("%s value must not be a list", v.typeName())
+		}
+		return ""
+	case map[string]any:
+		if v.Type != TYPE_MAP {
+			return fmt.Sprintf("%s value must not be a mapping", v.typeName())
+		}
+		return ""
+	}
+
+	value := FormatValue(structured)
+	switch v.Type {
+	case TYPE_LIST:
+		return fmt.Sprintf("%q is not a list (use [a, b] or one \"- item\" per line)", value)
+	case TYPE_MAP:
+		return fmt.Sprintf("%q is not a mapping (use key: value lines)", value)
+	case TYPE_INT:
+		if _, err := strconv.Atoi(strings.TrimSpace(value)); err != nil {
+			return fmt.Sprintf("%q is not an integer", value)
+		}
+	case TYPE_BOOL:
+		if _, err := strconv.ParseBool(strings.TrimSpace(value)); err != nil {
+			return fmt.Sprintf("%q is not a boolean (use true or false)", value)
+		}
+	case TYPE_ENUM:
+		found := false
+		for _, allowed := range v.Values {
+			if value == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("%q is not one of %s", value, strings.Join(v.Values, ", "))
+		}
+	case TYPE_STRING, TYPE_PATH:
+		if strings.Contains(value, "\n") {
+			return fmt.Sprintf("%s value must be a single line", v.typeName())
+		}
+	}
+
+	if v.Pattern != "" {
+		re, err := regexp.Compile(`^(?:` + v.Pattern + `)$`)
+		if err == nil && !re.MatchString(value) {
+			return fmt.Sprintf("%q does not match pattern %s", value, v.Pattern)
+		}
+	}
+
+	return ""
+}
+
+// Hint returns a short human readable description for the pick frontmatter
+func (v VariableSpec) Hint() string {
+	var parts []string
+	if v.Description != "" {
+		parts = append(parts, v.Description)
+	}
+	switch v.Type {
+	case TYPE_ENUM:
+		parts = append(parts, "one of: "+strings.Join(v.Values, ", "))
+	case "", TYPE_STRING:
+	default:
+		parts = append(parts, v.Type)
+	}
+	if v.Pattern != "" {
+		parts = append(parts, "pattern: "+v.Pattern)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// isEmptyValue reports whether a value counts as not provided
+func isEmptyValue(value any) bool {
+	switch v := value.(type) {
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return strings.TrimSpace(FormatValue(value)) == ""
+}
+
+// typeName returns the declared type, defaulting to string
+func (v VariableSpec) typeName() string {
+	if v.Type == "" {
+		return TYPE_STRING
+	}
+	return v.Type
+}
+
+// names returns the declared variable names in sorted order
+func (s Schema) names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
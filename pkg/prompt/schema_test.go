@@ -0,0 +1,106 @@
+package prompt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schema := Schema{
+		"LANGUAGE": {Type: TYPE_ENUM, Values: []string{"Go", "Python"}, Required: true},
+		"COUNT":    {Type: TYPE_INT},
+		"SECURITY": {Type: TYPE_BOOL},
+		"TICKET":   {Pattern: `[A-Z]+-[0-9]+`},
+		"PATH":     {Type: TYPE_PATH},
+		"NOTES":    {Type: TYPE_MULTILINE},
+		"FILES":    {Type: TYPE_LIST},
+		"OWNER":    {Type: TYPE_MAP},
+	}
+
+	tests := []struct {
+		name       string
+		values     map[string]any
+		violations []string
+	}{
+		{
+			name: "all valid",
+			values: map[string]any{
+				"LANGUAGE": "Go", "COUNT": "3", "SECURITY": "true",
+				"TICKET": "ABC-12", "PATH": "src/main.go", "NOTES": "line 1\nline 2",
+				"FILES": []any{"a.go"}, "OWNER": map[string]any{"name": "ada"},
+			},
+		},
+		{
+			name: "structured values must match their type",
+			values: map[string]any{
+				"LANGUAGE": []any{"Go"}, "FILES": "a.go", "OWNER": []any{"ada"},
+			},
+			violations: []string{"FILES", "LANGUAGE", "OWNER"},
+		},
+		{
+			name:   "empty optional values are accepted",
+			values: map[string]any{"LANGUAGE": "Python", "COUNT": ""},
+		},
+		{
+			name: "every violation is reported",
+			values: map[string]any{
+				"LANGUAGE": "go", "COUNT": "three", "SECURITY": "maybe",
+				"TICKET": "abc-12", "PATH": "a\nb",
+			},
+			violations: []string{"COUNT", "LANGUAGE", "PATH", "SECURITY", "TICKET"},
+		},
+		{
+			name:       "missing required value",
+			values:     map[string]any{},
+			violations: []string{"LANGUAGE"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate(tt.values)
+			if len(tt.violations) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() error = %v, want *ValidationError", err)
+			}
+			var names []string
+			for _, v := range validationErr.Violations {
+				names = append(names, v.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.violations, ",") {
+				t.Errorf("Validate() violations = %v, want %v", names, tt.violations)
+			}
+		})
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema Schema
+		valid  bool
+	}{
+		{"empty schema", Schema{}, true},
+		{"known types", Schema{"A": {Type: TYPE_STRING}, "B": {Type: TYPE_PATH}}, true},
+		{"unknown type", Schema{"A": {Type: "float"}}, false},
+		{"enum without values", Schema{"A": {Type: TYPE_ENUM}}, false},
+		{"invalid pattern", Schema{"A": {Pattern: "("}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schema.Check()
+			if (err == nil) != tt.valid {
+				t.Errorf("Check() error = %v, want valid = %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/dhamidi/proompt/pkg/copier"
+)
+
+var (
+	// ErrCommandsDisabled is returned by @cmd unless running commands was enabled
+	ErrCommandsDisabled = errors.New("running commands is disabled; set PROOMPT_ALLOW_CMD=1 to enable @cmd")
+	// ErrUntrustedCommand is returned by @cmd in prompts with content from a bundle
+	ErrUntrustedCommand = errors.New("prompts from bundles cannot run commands")
+)
+
+// ValueSource computes a placeholder value at render time, e.g. for ${DIFF:-@cmd:git diff}
+type ValueSource interface {
+	Resolve(arg string) (string, error)
+}
+
+// ValueSourceFunc adapts a function to the ValueSource interface
+type ValueSourceFunc func(arg string) (string, error)
+
+// Resolve implements ValueSource
+func (f ValueSourceFunc) Resolve(arg string) (string, error) {
+	return f(arg)
+}
+
+// SourceError describes a value source that failed to produce a value
+type SourceError struct {
+	Placeholder string
+	Source      string
+	Arg         string
+	Err         error
+}
+
+// Error implements error
+func (e *SourceError) Error() string {
+	ref := "@" + e.Source
+	if e.Arg != "" {
+		ref += ":" + e.Arg
+	}
+	if e.Placeholder == "" {
+		return fmt.Sprintf("%s: %v", ref, e.Err)
+	}
+	return fmt.Sprintf("placeholder %s: %s: %v", e.Placeholder, ref, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// SourceRegistry maps source names to value sources
+type SourceRegistry struct {
+	sources map[string]ValueSource
+}
+
+// NewSourceRegistry creates an empty SourceRegistry
+func NewSourceRegistry() *SourceRegistry {
+	return &SourceRegistry{
+		sources: make(map[string]ValueSource),
+	}
+}
+
+// NewDefaultSourceRegistry creates a SourceRegistry with the built-in
+// @file, @cmd, @env and @clipboard sources. @cmd is disabled; register a
+// CommandSource with Allowed set to run commands
+func NewDefaultSourceRegistry(readFS fs.ReadFileFS, paster copier.Paster) *SourceRegistry {
+	registry := NewSourceRegistry()
+	registry.Register("file", &FileSource{FS: readFS})
+	registry.Register("cmd", &CommandSource{})
+	registry.Register("env", ValueSourceFunc(envSource))
+	registry.Register("clipboard", ValueSourceFunc(func(arg string) (string, error) {
+		return paster.Paste()
+	}))
+	return registry
+}
+
+// Register adds or replaces the source available as @name
+func (r *SourceRegistry) Register(name string, source ValueSource) {
+	r.sources[name] = source
+}
+
+// Lookup returns the source registered as name
+func (r *SourceRegistry) Lookup(name string) (ValueSource, bool) {
+	if r == nil {
+		return nil, false
+	}
+	source, ok := r.sources[name]
+	return source, ok
+}
+
+// untrusted returns a copy of the registry for content from a bundle, in which
+// a registered @cmd fails with ErrUntrustedCommand instead of running
+func (r *SourceRegistry) untrusted() *SourceRegistry {
+	copied := NewSourceRegistry()
+	for name, source := range r.sources {
+		copied.sources[name] = source
+	}
+	if _, ok := copied.sources["cmd"]; ok {
+		copied.sources["cmd"] = ValueSourceFunc(func(string) (string, error) {
+			return "", ErrUntrustedCommand
+		})
+	}
+	return copied
+}
+
+// parseReference splits "@name:arg" or "@name" into its parts if name is a registered source.
+// Anything else, e.g. "@someone", is a literal value
+func (r *SourceRegistry) parseReference(value string) (name, arg string, ok bool) {
+	if !strings.HasPrefix(value, "@") {
+		return "", "", false
+	}
+	name, arg, _ = strings.Cut(value[1:], ":")
+	if _, registered := r.Lookup(name); !registered {
+		return "", "", false
+	}
+	return name, arg, true
+}
+
+// sourceCache resolves source references at most once per render
+type sourceCache struct {
+	registry *SourceRegistry
+	results  map[string]string
+}
+
+// newSourceCache creates a cache for a single render
+func newSourceCache(registry *SourceRegistry) *sourceCache {
+	return &sourceCache{
+		registry: registry,
+		results:  make(map[string]string),
+	}
+}
+
+// resolve returns the computed value for a source reference, or value itself
+// if it does not reference a registered source
+func (c *sourceCache) resolve(placeholder, value string) (string, error) {
+	name, arg, ok := c.registry.parseReference(value)
+	if !ok {
+		return value, nil
+	}
+
+	if result, cached := c.results[value]; cached {
+		return result, nil
+	}
+
+	source, _ := c.registry.Lookup(name)
+	result, err := source.Resolve(arg)
+	if err != nil {
+		return "", &SourceError{Placeholder: placeholder, Source: name, Arg: arg, Err: err}
+	}
+
+	c.results[value] = result
+	return result, nil
+}
+
+// FileSource reads a file, optionally limited to a line range: path#L10-40 or path#L10
+type FileSource struct {
+	FS fs.ReadFileFS
+}
+
+// Resolve implements ValueSource
+func (s *FileSource) Resolve(arg string) (string, error) {
+	path, lines, hasLines := strings.Cut(arg, "#")
+	if path == "" {
+		return "", fmt.Errorf("missing file path")
+	}
+
+	data, err := s.FS.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !hasLines {
+		return string(data), nil
+	}
+
+	start, end, err := parseLineRange(lines)
+	if err != nil {
+		return "", err
+	}
+
+	fileLines := strings.SplitAfter(string(data), "\n")
+	if start > len(fileLines) {
+		return "", fmt.Errorf("line %d is beyond the end of %s", start, path)
+	}
+	if end > len(fileLines) {
+		end = len(fileLines)
+	}
+	return strings.Join(fileLines[start-1:end], ""), nil
+}
+
+// parseLineRange parses "L10-40", "L10-L40" or "L10" into 1-based inclusive bounds
+func parseLineRange(spec string) (int, int, error) {
+	from, to, isRange := strings.Cut(strings.TrimPrefix(spec, "L"), "-")
+	start, err := strconv.Atoi(from)
+	if err != nil || start < 1 {
+		return 0, 0, fmt.Errorf("invalid line range %q", spec)
+	}
+	if !isRange {
+		return start, start, nil
+	}
+	end, err := strconv.Atoi(strings.TrimPrefix(to, "L"))
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("invalid line range %q", spec)
+	}
+	return start, end, nil
+}
+
+// CommandSource runs @cmd:command with sh -c. Any prompt, including those
+// committed to a project, could run anything, so commands only run if Allowed
+type CommandSource struct {
+	Allowed bool
+}
+
+// Resolve implements ValueSource
+func (s *CommandSource) Resolve(command string) (string, error) {
+	if !s.Allowed {
+		return "", ErrCommandsDisabled
+	}
+	return runCommandSource(command)
+}
+
+// runCommandSource runs a shell command and returns its output without trailing newlines
+func runCommandSource(command string) (string, error) {
+	if strings.TrimSpace(command) == "" {
+		return "", fmt.Errorf("missing command")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// envSource returns the value of an environment variable, failing if it is not set
+func envSource(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
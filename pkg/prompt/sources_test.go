@@ -0,0 +1,161 @@
+package prompt
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dhamidi/proompt/pkg/copier"
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+func TestFileSource(t *testing.T) {
+	fs := filesystem.NewFakeFilesystem()
+	fs.MapFS["src/main.go"] = &fstest.MapFile{
+		Data: []byte("line 1\nline 2\nline 3\nline 4\n"),
+		Mode: 0644,
+	}
+	source := &FileSource{FS: fs}
+
+	tests := []struct {
+		arg       string
+		expected  string
+		expectErr bool
+	}{
+		{arg: "src/main.go", expected: "line 1\nline 2\nline 3\nline 4\n"},
+		{arg: "src/main.go#L2-3", expected: "line 2\nline 3\n"},
+		{arg: "src/main.go#L2-L3", expected: "line 2\nline 3\n"},
+		{arg: "src/main.go#L4", expected: "line 4\n"},
+		{arg: "src/main.go#L3-99", expected: "line 3\nline 4\n"},
+		{arg: "src/main.go#L3-1", expectErr: true},
+		{arg: "src/main.go#Lx", expectErr: true},
+		{arg: "missing.go", expectErr: true},
+		{arg: "", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			result, err := source.Resolve(tt.arg)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Resolve(%q) expected error", tt.arg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q) error = %v", tt.arg, err)
+			}
+			if result != tt.expected {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.arg, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDefaultSourceRegistry(t *testing.T) {
+	paster := copier.NewFakePaster()
+	paster.Content = "selected text"
+	registry := NewDefaultSourceRegistry(filesystem.NewFakeFilesystem(), paster)
+	registry.Register("cmd", &CommandSource{Allowed: true})
+	os.Setenv("PROOMPT_TEST_SOURCE", "from env")
+	defer os.Unsetenv("PROOMPT_TEST_SOURCE")
+
+	parser := NewDefaultParser()
+	parser.Sources = registry
+
+	result, err := parser.SubstitutePlaceholders(
+		"${A:-@env:PROOMPT_TEST_SOURCE}|${B:-@cmd:printf 'out\\n\\n'}|${C:-@clipboard}|${D:-@someone}",
+		map[string]any{},
+	)
+	if err != nil {
+		t.Fatalf("SubstitutePlaceholders() error = %v", err)
+	}
+	if expected := "from env|out|selected text|@someone"; result != expected {
+		t.Errorf("SubstitutePlaceholders() = %q, want %q", result, expected)
+	}
+
+	// Text produced by expansions is never run as a source, whether it comes from values or defaults
+	result, err = parser.SubstitutePlaceholders(
+		"${A:-${B}}|${C:-@c${D:-md}:printf out}|${E:-${F:-@env:PROOMPT_TEST_SOURCE}}",
+		map[string]any{"B": "@env:PROOMPT_TEST_SOURCE"},
+	)
+	if expected := "@env:PROOMPT_TEST_SOURCE|@cmd:printf out|from env"; err != nil || result != expected {
+		t.Errorf("SubstitutePlaceholders() = %q, %v, want %q", result, err, expected)
+	}
+
+	// Explicit values win and never run the source
+	paster.PasteCount = 0
+	result, err = parser.SubstitutePlaceholders("${C:-@clipboard}", map[string]any{"C": "given"})
+	if err != nil || result != "given" || paster.PasteCount != 0 {
+		t.Errorf("SubstitutePlaceholders() = %q, %v with %d pastes, want \"given\" without pasting", result, err, paster.PasteCount)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	parser := NewDefaultParser()
+	parser.Sources = NewDefaultSourceRegistry(filesystem.NewFakeFilesystem(), copier.NewFakePaster())
+
+	_, err := parser.SubstitutePlaceholders("${USER:-@env:PROOMPT_TEST_UNSET_VARIABLE}", map[string]any{})
+	var sourceErr *SourceError
+	if !errors.As(err, &sourceErr) {
+		t.Fatalf("SubstitutePlaceholders() error = %v, want *SourceError", err)
+	}
+	if sourceErr.Placeholder != "USER" || sourceErr.Source != "env" || sourceErr.Arg != "PROOMPT_TEST_UNSET_VARIABLE" {
+		t.Errorf("SourceError = %+v", sourceErr)
+	}
+
+	_, err = parser.SubstitutePlaceholders("${OUT:-@cmd:echo ran}", map[string]any{})
+	if !errors.Is(err, ErrCommandsDisabled) {
+		t.Errorf("SubstitutePlaceholders() error = %v, want ErrCommandsDisabled by default", err)
+	}
+
+	parser.Sources.Register("cmd", &CommandSource{Allowed: true})
+	_, err = parser.SubstitutePlaceholders("${OUT:-@cmd:echo oops >&2; exit 3}", map[string]any{})
+	if !errors.As(err, &sourceErr) || sourceErr.Source != "cmd" {
+		t.Fatalf("SubstitutePlaceholders() error = %v, want *SourceError from cmd", err)
+	}
+
+	bundle := parser.ForSource(BUNDLE_SOURCE)
+	if _, err = bundle.SubstitutePlaceholders("${OUT:-@cmd:echo ran}", map[string]any{}); !errors.Is(err, ErrUntrustedCommand) {
+		t.Errorf("SubstitutePlaceholders() for a bundle error = %v, want ErrUntrustedCommand", err)
+	}
+	if output, err := parser.SubstitutePlaceholders("${OUT:-@cmd:echo ran}", map[string]any{}); err != nil || output != "ran" {
+		t.Errorf("ForSource() changed the original parser: %q, %v", output, err)
+	}
+}
+
+func TestResolveDefaultsCachesPerRender(t *testing.T) {
+	calls := 0
+	registry := NewSourceRegistry()
+	registry.Register("count", ValueSourceFunc(func(arg string) (string, error) {
+		calls++
+		return "computed " + arg, nil
+	}))
+
+	parser := NewDefaultParser()
+	parser.Sources = registry
+
+	placeholders, err := parser.ParsePlaceholders("${A:-@count:x} ${B:-@count:x} ${C:-@count:y} ${D:-literal} ${E}")
+	if err != nil {
+		t.Fatalf("ParsePlaceholders() error = %v", err)
+	}
+
+	resolved, err := parser.ResolveDefaults(placeholders)
+	if err != nil {
+		t.Fatalf("ResolveDefaults() error = %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected 2 source calls, got %d", calls)
+	}
+	if resolved[0].DefaultValue != "computed x" || resolved[0].Source != "@count:x" {
+		t.Errorf("resolved[0] = %+v", resolved[0])
+	}
+	if resolved[3].DefaultValue != "literal" || resolved[3].Source != "" {
+		t.Errorf("literal default should be unchanged, got %+v", resolved[3])
+	}
+	if placeholders[0].DefaultValue != "@count:x" {
+		t.Error("ResolveDefaults() should not modify its input")
+	}
+}
@@ -0,0 +1,386 @@
+package prompt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Position is a location in a template; Line and Column are 1-based and Column counts runes
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// String formats the position as line:column
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is the source range [Start, End) covered by a node
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// Node is an element of a parsed template
+type Node interface {
+	Span() Span
+	Source() string // exact source text of the node
+}
+
+// TextNode is literal text
+type TextNode struct {
+	Text string
+	span Span
+}
+
+// EscapeNode is a $$ escape that renders as a single $
+type EscapeNode struct {
+	span Span
+}
+
+// PlaceholderNode is a ${...} parameter expansion
+type PlaceholderNode struct {
+	Name     string
+	Operator string
+	Operand  *Template // default, alternate, message or pattern; nil if the operator has none
+	Filters  []string  // names of the |filter steps applied to the value, in order
+	raw      string
+	span     Span
+	exp      expansion
+}
+
+// DirectiveNode is a ${@name:arg} directive such as ${@include:conventions}
+type DirectiveNode struct {
+	Name string
+	Arg  string
+	raw  string
+	span Span
+}
+
+// Span implements Node
+func (n *TextNode) Span() Span { return n.span }
+
+// Source implements Node
+func (n *TextNode) Source() string { return n.Text }
+
+// Span implements Node
+func (n *EscapeNode) Span() Span { return n.span }
+
+// Source implements Node
+func (n *EscapeNode) Source() string { return "$$" }
+
+// Span implements Node
+func (n *PlaceholderNode) Span() Span { return n.span }
+
+// Source implements Node
+func (n *PlaceholderNode) Source() string { return n.raw }
+
+// Span implements Node
+func (n *DirectiveNode) Span() Span { return n.span }
+
+// Source implements Node
+func (n *DirectiveNode) Source() string { return n.raw }
+
+// Diagnostic reports a malformed construct in a template
+type Diagnostic struct {
+	Span    Span
+	Message string
+}
+
+// String formats the diagnostic as line:column: message
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Span.Start, d.Message)
+}
+
+// DiagnosticsError is returned when a template contains malformed placeholders
+type DiagnosticsError struct {
+	Diagnostics []Diagnostic
+}
+
+// Error implements error
+func (e *DiagnosticsError) Error() string {
+	var buf strings.Builder
+	buf.WriteString("malformed placeholders (use $$ for a literal $):")
+	for _, d := range e.Diagnostics {
+		buf.WriteString("\n  " + d.String())
+	}
+	return buf.String()
+}
+
+// Template is a parsed prompt template. Malformed placeholders are kept as
+// text nodes and reported in Diagnostics, so String always returns the source
+type Template struct {
+	Nodes       []Node
+	Diagnostics []Diagnostic
+}
+
+// String reassembles the template source byte for byte
+func (t *Template) String() string {
+	var buf strings.Builder
+	for _, node := range t.Nodes {
+		buf.WriteString(node.Source())
+	}
+	return buf.String()
+}
+
+// Err returns a *DiagnosticsError if the template has diagnostics
+func (t *Template) Err() error {
+	if len(t.Diagnostics) == 0 {
+		return nil
+	}
+	return &DiagnosticsError{Diagnostics: t.Diagnostics}
+}
+
+// tokenKind identifies a lexer token
+type tokenKind int
+
+const (
+	tokText   tokenKind = iota // literal text
+	tokEscape                  // $$
+	tokOpen                    // ${
+	tokClose                   // } inside a placeholder
+	tokEOF
+)
+
+// token is a lexed range of the source
+type token struct {
+	kind       tokenKind
+	start, end int
+}
+
+// lexer splits template source into tokens. A closing brace is only a token
+// while inside a placeholder, so stray braces in prose stay text
+type lexer struct {
+	src   string
+	pos   int
+	depth int
+}
+
+// next returns the next token
+func (l *lexer) next() token {
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, start: start, end: start}
+	}
+
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "$$"):
+		l.pos += 2
+		return token{kind: tokEscape, start: start, end: l.pos}
+	case strings.HasPrefix(l.src[l.pos:], "${"):
+		l.pos += 2
+		l.depth++
+		return token{kind: tokOpen, start: start, end: l.pos}
+	case l.depth > 0 && l.src[l.pos] == '}':
+		l.pos++
+		l.depth--
+		return token{kind: tokClose, start: start, end: l.pos}
+	}
+
+	for l.pos < len(l.src) {
+		rest := l.src[l.pos:]
+		if strings.HasPrefix(rest, "$$") || strings.HasPrefix(rest, "${") || (l.depth > 0 && rest[0] == '}') {
+			break
+		}
+		l.pos++
+	}
+	return token{kind: tokText, start: start, end: l.pos}
+}
+
+// templateParser builds a Template from lexer tokens
+type templateParser struct {
+	src        string
+	lx         *lexer
+	lineStarts []int
+	tmpl       *Template
+	filters    *FilterRegistry // filters a pipe in a placeholder may start
+}
+
+// defaultFilters holds the built-in filters that ParseTemplate recognizes
+var defaultFilters = NewDefaultFilterRegistry()
+
+// ParseTemplate parses template source into nodes with positions and diagnostics.
+// Only the built-in filters are recognized after a pipe
+func ParseTemplate(src string) *Template {
+	return parseTemplate(src, defaultFilters)
+}
+
+// parseTemplate parses template source, recognizing the filters of filters
+func parseTemplate(src string, filters *FilterRegistry) *Template {
+	p := &templateParser{
+		src:        src,
+		lx:         &lexer{src: src},
+		lineStarts: []int{0},
+		tmpl:       &Template{},
+		filters:    filters,
+	}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			p.lineStarts = append(p.lineStarts, i+1)
+		}
+	}
+
+	p.run()
+	return p.tmpl
+}
+
+// run parses tokens until the end of input
+func (p *templateParser) run() {
+	for {
+		tok := p.lx.next()
+		switch tok.kind {
+		case tokEOF:
+			return
+		case tokText:
+			p.text(tok.start, tok.end)
+		case tokEscape:
+			p.tmpl.Nodes = append(p.tmpl.Nodes, &EscapeNode{span: p.span(tok.start, tok.end)})
+		case tokOpen:
+			p.placeholder(tok)
+		}
+	}
+}
+
+// parseRange parses src[start:end] as a nested template, e.g. the default of
+// ${A:-${B}}. Spans stay relative to the whole source and diagnostics are
+// reported on the outer template
+func (p *templateParser) parseRange(start, end int) *Template {
+	child := &templateParser{
+		src:        p.src,
+		lx:         &lexer{src: p.src[:end], pos: start},
+		lineStarts: p.lineStarts,
+		tmpl:       &Template{},
+		filters:    p.filters,
+	}
+	child.run()
+
+	p.tmpl.Diagnostics = append(p.tmpl.Diagnostics, child.tmpl.Diagnostics...)
+	child.tmpl.Diagnostics = nil
+	return child.tmpl
+}
+
+// placeholder parses a ${...} expression after its opening token. Nested
+// expressions are skipped here and parsed as part of the operand
+func (p *templateParser) placeholder(open token) {
+	depth := p.lx.depth
+	for {
+		tok := p.lx.next()
+		switch tok.kind {
+		case tokClose:
+			if p.lx.depth < depth {
+				p.finishPlaceholder(open.start, tok.end)
+				return
+			}
+		case tokEOF:
+			// Recover by treating "${" as text and lexing again right after it
+			p.diagnose(open.start, open.end, "unclosed placeholder, missing '}'")
+			p.text(open.start, open.end)
+			p.lx.pos, p.lx.depth = open.end, depth-1
+			return
+		}
+	}
+}
+
+// finishPlaceholder turns a complete ${...} expression into a node
+func (p *templateParser) finishPlaceholder(start, end int) {
+	raw := p.src[start:end]
+	bodyStart := start + 2
+	body := p.src[bodyStart : end-1]
+
+	var message string
+	switch {
+	case body == "":
+		message = "empty placeholder ${}"
+	case strings.HasPrefix(body, "@"):
+		name, arg, _ := strings.Cut(body[1:], ":")
+		if name != "include" {
+			message = fmt.Sprintf("unknown directive @%s", name)
+		} else if strings.TrimSpace(arg) == "" {
+			message = "include directive needs a prompt name"
+		} else if strings.Contains(arg, "${") {
+			message = "include directive cannot contain placeholders"
+		} else {
+			p.tmpl.Nodes = append(p.tmpl.Nodes, &DirectiveNode{
+				Name: name,
+				Arg:  strings.TrimSpace(arg),
+				raw:  raw,
+				span: p.span(start, end),
+			})
+			return
+		}
+	default:
+		expression, filters, unknown := splitFilters(body, p.filters)
+		exp, err := parseExpansion(expression)
+		if err != nil && unknown != "" {
+			err = fmt.Errorf("%w %q in ${%s}", ErrUnknownFilter, unknown, body)
+		}
+		if err == nil {
+			exp.filters = filters
+			if exp.hasWord() {
+				exp.word = p.parseRange(bodyStart+exp.wordOffset, bodyStart+exp.wordOffset+len(exp.Word))
+			}
+			if exp.hasReplacement() {
+				exp.replace = p.parseRange(bodyStart+exp.replaceOffset, bodyStart+exp.replaceOffset+len(exp.Replace))
+			}
+			p.tmpl.Nodes = append(p.tmpl.Nodes, &PlaceholderNode{
+				Name:     exp.Name,
+				Operator: exp.Op,
+				Operand:  exp.word,
+				Filters:  filterNames(filters),
+				raw:      raw,
+				span:     p.span(start, end),
+				exp:      exp,
+			})
+			return
+		}
+		message = err.Error()
+	}
+
+	p.diagnose(start, end, message)
+	p.text(start, end)
+}
+
+// filterNames returns the names of a filter pipeline
+func filterNames(calls []filterCall) []string {
+	var names []string
+	for _, call := range calls {
+		names = append(names, call.Name)
+	}
+	return names
+}
+
+// text appends literal source text, merging with a preceding text node
+func (p *templateParser) text(start, end int) {
+	if start >= end {
+		return
+	}
+	if n := len(p.tmpl.Nodes); n > 0 {
+		if prev, ok := p.tmpl.Nodes[n-1].(*TextNode); ok && prev.span.End.Offset == start {
+			prev.Text += p.src[start:end]
+			prev.span.End = p.position(end)
+			return
+		}
+	}
+	p.tmpl.Nodes = append(p.tmpl.Nodes, &TextNode{Text: p.src[start:end], span: p.span(start, end)})
+}
+
+// diagnose records a diagnostic for the given source range
+func (p *templateParser) diagnose(start, end int, message string) {
+	p.tmpl.Diagnostics = append(p.tmpl.Diagnostics, Diagnostic{Span: p.span(start, end), Message: message})
+}
+
+// span converts byte offsets into a Span
+func (p *templateParser) span(start, end int) Span {
+	return Span{Start: p.position(start), End: p.position(end)}
+}
+
+// position converts a byte offset into a Position
+func (p *templateParser) position(offset int) Position {
+	line := sort.Search(len(p.lineStarts), func(i int) bool { return p.lineStarts[i] > offset }) - 1
+	column := utf8.RuneCountInString(p.src[p.lineStarts[line]:offset]) + 1
+	return Position{Offset: offset, Line: line + 1, Column: column}
+}
@@ -0,0 +1,133 @@
+package prompt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseTemplateRoundTrip(t *testing.T) {
+	sources := []string{
+		"",
+		"Hello world!",
+		"Hello ${NAME:-World}!\nPrice: $$100",
+		"${A}${B}$$${C:-x}",
+		"Unclosed ${NAME and more text",
+		"Empty ${} placeholder",
+		"Bad ${foo bar} name",
+		"Nested ${VAR:-${NESTED}} value",
+		"Stray } brace and $ sign",
+		"${@include:conventions} and ${@unknown:x}",
+		"Trailing dollar $",
+		"Ünïcödé ${NAME^^}\r\nline",
+	}
+
+	for _, src := range sources {
+		tmpl := ParseTemplate(src)
+		if got := tmpl.String(); got != src {
+			t.Errorf("ParseTemplate(%q).String() = %q, want the source unchanged", src, got)
+		}
+	}
+}
+
+func TestParseTemplateNodes(t *testing.T) {
+	tmpl := ParseTemplate("Hi ${NAME:-you},\n$$${@include:footer}")
+
+	if len(tmpl.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", tmpl.Diagnostics)
+	}
+
+	var kinds []string
+	for _, node := range tmpl.Nodes {
+		kinds = append(kinds, reflect.TypeOf(node).Elem().Name())
+	}
+	expected := []string{"TextNode", "PlaceholderNode", "TextNode", "EscapeNode", "DirectiveNode"}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Fatalf("node kinds = %v, want %v", kinds, expected)
+	}
+
+	placeholder := tmpl.Nodes[1].(*PlaceholderNode)
+	if placeholder.Name != "NAME" || placeholder.Operator != OP_DEFAULT {
+		t.Errorf("placeholder = %+v", placeholder)
+	}
+	if span := placeholder.Span(); span.Start != (Position{Offset: 3, Line: 1, Column: 4}) || span.End.Offset != 15 {
+		t.Errorf("placeholder span = %+v", span)
+	}
+
+	directive := tmpl.Nodes[4].(*DirectiveNode)
+	if directive.Name != "include" || directive.Arg != "footer" {
+		t.Errorf("directive = %+v", directive)
+	}
+	if start := directive.Span().Start; start.Line != 2 || start.Column != 3 {
+		t.Errorf("directive position = %v, want 2:3", start)
+	}
+}
+
+func TestParseTemplateDiagnostics(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		{"Hello ${NAME", []string{"1:7: unclosed placeholder, missing '}'"}},
+		{"a\n  ${}", []string{"2:3: empty placeholder ${}"}},
+		{"${foo bar}", []string{"1:1: invalid placeholder name in ${foo bar}"}},
+		{"${A:-x\n${}}", []string{"2:1: empty placeholder ${}"}},
+		{"${@include:${NAME}}", []string{"1:1: include directive cannot contain placeholders"}},
+		{"${@nope:x} ${@include:}", []string{"1:1: unknown directive @nope", "1:12: include directive needs a prompt name"}},
+		{"${NAME^x}", []string{"1:1: unsupported case modification in ${NAME^x}"}},
+		{"Hello\n  ${NAME|shout}", []string{`2:3: unknown filter "shout" in ${NAME|shout}`}},
+		{"${ü ${OK}", []string{"1:1: unclosed placeholder, missing '}'"}},
+	}
+
+	for _, tt := range tests {
+		tmpl := ParseTemplate(tt.src)
+		var got []string
+		for _, d := range tmpl.Diagnostics {
+			got = append(got, d.String())
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseTemplate(%q) diagnostics = %q, want %q", tt.src, got, tt.expected)
+		}
+	}
+}
+
+func TestParseTemplateRecoversAfterUnclosedPlaceholder(t *testing.T) {
+	tmpl := ParseTemplate("${ü ${OK}")
+
+	var names []string
+	for _, node := range tmpl.Nodes {
+		if placeholder, ok := node.(*PlaceholderNode); ok {
+			names = append(names, placeholder.Name)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"OK"}) {
+		t.Errorf("placeholders = %v, want [OK]", names)
+	}
+}
+
+func TestDefaultParserKeepsMalformedPlaceholders(t *testing.T) {
+	parser := NewDefaultParser()
+	content := "echo ${arr[@]} ${x.y} ${}\nHello ${OK} ${NAME"
+
+	placeholders, err := parser.ParsePlaceholders(content)
+	if err != nil {
+		t.Fatalf("ParsePlaceholders() error = %v, want malformed placeholders to be skipped", err)
+	}
+	if len(placeholders) != 1 || placeholders[0].Name != "OK" {
+		t.Errorf("ParsePlaceholders() = %+v, want only OK", placeholders)
+	}
+
+	var diagnosticsErr *DiagnosticsError
+	if err := ParseTemplate(content).Err(); !errors.As(err, &diagnosticsErr) || len(diagnosticsErr.Diagnostics) != 4 {
+		t.Errorf("Template.Err() = %v, want 4 diagnostics", err)
+	}
+
+	// Substitution keeps malformed placeholders verbatim
+	result, err := parser.SubstitutePlaceholders(content, map[string]any{"OK": "fine"})
+	if err != nil {
+		t.Fatalf("SubstitutePlaceholders() error = %v", err)
+	}
+	if result != "echo ${arr[@]} ${x.y} ${}\nHello fine ${NAME" {
+		t.Errorf("SubstitutePlaceholders() = %q", result)
+	}
+}
@@ -0,0 +1,160 @@
+package prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+// ErrNotInTrash is returned when no removed prompt matches a name
+var ErrNotInTrash = errors.New("prompt not found in trash")
+
+// TrashEntry is a removed prompt together with where it was stored
+type TrashEntry struct {
+	ID        string    `json:"-"`
+	Name      string    `json:"name"`
+	Source    string    `json:"source"` // the level the prompt was removed from
+	Path      string    `json:"path"`   // the absolute original file path
+	DeletedAt time.Time `json:"deleted_at"`
+	Content   string    `json:"content"`
+	Presets   string    `json:"presets,omitempty"` // the presets file of the prompt, if it had one
+}
+
+// Trash keeps removed prompts as JSON files in a directory so they can be restored
+type Trash struct {
+	Filesystem filesystem.Filesystem
+	Dir        string
+	Now        func() time.Time
+}
+
+// NewTrash creates a Trash in $XDG_DATA_HOME/proompt/trash
+func NewTrash(fs filesystem.Filesystem) (*Trash, error) {
+	dataDir, err := fs.UserDataDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Trash{
+		Filesystem: fs,
+		Dir:        filepath.Join(dataDir, "proompt", "trash"),
+		Now:        time.Now,
+	}, nil
+}
+
+// Put records info and the content of its presets file as removed under its
+// absolute path, so it can be restored from any directory. It does not remove
+// the prompt file itself
+func (t *Trash) Put(info *PromptInfo, presets string) (*TrashEntry, error) {
+	path, err := filepath.Abs(info.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", info.Path, err)
+	}
+
+	now := t.Now().UTC()
+	entry := &TrashEntry{
+		// The name makes IDs unique between prompts removed in the same instant
+		ID:        now.Format("20060102T150405.000000000Z") + "-" + strings.ReplaceAll(info.Name, "/", "_"),
+		Name:      info.Name,
+		Source:    info.Source,
+		Path:      path,
+		DeletedAt: now,
+		Content:   info.Content,
+		Presets:   presets,
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Filesystem.MkdirAll(t.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := t.Filesystem.WriteFile(t.entryPath(entry.ID), data, 0644); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// List returns the removed prompts, most recently removed first
+func (t *Trash) List() ([]TrashEntry, error) {
+	files, err := t.Filesystem.ReadDir(t.Dir)
+	if err != nil {
+		return nil, nil // Nothing has been removed yet
+	}
+
+	var entries []TrashEntry
+	for _, file := range files {
+		id, ok := strings.CutSuffix(file.Name(), ".json")
+		if file.IsDir() || !ok {
+			continue
+		}
+		data, err := t.Filesystem.ReadFile(t.entryPath(id))
+		if err != nil {
+			continue // Skip entries that can't be read
+		}
+		var entry TrashEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue // Skip files that are not trash entries
+		}
+		entry.ID = id
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].DeletedAt.After(entries[j].DeletedAt)
+	})
+	return entries, nil
+}
+
+// Find returns the most recently removed prompt called name. A source-qualified
+// name such as user:review only matches prompts removed from that level
+func (t *Trash) Find(name string) (*TrashEntry, error) {
+	entries, err := t.List()
+	if err != nil {
+		return nil, err
+	}
+
+	source, bare := SplitQualifiedName(name)
+	for _, entry := range entries {
+		if entry.Name == bare && (source == "" || entry.Source == source) {
+			return &entry, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotInTrash, name)
+}
+
+// Remove deletes an entry from the trash for good
+func (t *Trash) Remove(entry *TrashEntry) error {
+	return t.Filesystem.Remove(t.entryPath(entry.ID))
+}
+
+// Purge deletes the entries removed longer than olderThan ago and returns them
+func (t *Trash) Purge(olderThan time.Duration) ([]TrashEntry, error) {
+	entries, err := t.List()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := t.Now().Add(-olderThan)
+	var purged []TrashEntry
+	for _, entry := range entries {
+		if entry.DeletedAt.After(cutoff) {
+			continue
+		}
+		if err := t.Remove(&entry); err != nil {
+			return purged, fmt.Errorf("failed to purge %s: %w", entry.Name, err)
+		}
+		purged = append(purged, entry)
+	}
+	return purged, nil
+}
+
+// entryPath returns the path of the file holding the entry id
+func (t *Trash) entryPath(id string) string {
+	return t.Dir + "/" + id + ".json"
+}
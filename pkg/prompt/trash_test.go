@@ -0,0 +1,143 @@
+package prompt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dhamidi/proompt/pkg/filesystem"
+)
+
+func TestTrash(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	trash := &Trash{
+		Filesystem: filesystem.NewRealFilesystem("/"),
+		Dir:        t.TempDir() + "/trash",
+		Now:        func() time.Time { return now },
+	}
+
+	if entries, err := trash.List(); err != nil || len(entries) != 0 {
+		t.Fatalf("List() of an empty trash = %v, %v", entries, err)
+	}
+
+	if _, err := trash.Put(&PromptInfo{Name: "review", Source: "user", Path: "/user/review.md", Content: "Old review"}, ""); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+	now = now.Add(10 * 24 * time.Hour)
+	if _, err := trash.Put(&PromptInfo{Name: "review", Source: "project-local", Path: "/local/review.md", Content: "Local review"}, ""); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+
+	now = now.Add(time.Second)
+	relative, err := trash.Put(&PromptInfo{Name: "draft", Source: "directory", Path: "prompts/draft.md", Content: "Draft"}, "")
+	if err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+	if expected, _ := filepath.Abs("prompts/draft.md"); relative.Path != expected {
+		t.Errorf("Put() path = %q, want the absolute path %q", relative.Path, expected)
+	}
+	trash.Remove(relative)
+
+	entries, err := trash.List()
+	if err != nil || len(entries) != 2 || entries[0].Source != "project-local" {
+		t.Fatalf("List() = %+v, %v; want the newest entry first", entries, err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		err     error
+	}{
+		{name: "review", content: "Local review"},
+		{name: "user:review", content: "Old review"},
+		{name: "project:review", err: ErrNotInTrash},
+		{name: "missing", err: ErrNotInTrash},
+	}
+	for _, tt := range tests {
+		entry, err := trash.Find(tt.name)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Find(%q) error = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && entry.Content != tt.content {
+			t.Errorf("Find(%q) = %q, want %q", tt.name, entry.Content, tt.content)
+		}
+	}
+
+	purged, err := trash.Purge(7 * 24 * time.Hour)
+	if err != nil || len(purged) != 1 || purged[0].Source != "user" {
+		t.Errorf("Purge() = %+v, %v; want the older entry", purged, err)
+	}
+	if entries, _ := trash.List(); len(entries) != 1 {
+		t.Errorf("Expected one entry after purging, got %+v", entries)
+	}
+}
+
+func TestDefaultManagerDeleteAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	fs := filesystem.NewRealFilesystem(dir)
+	if err := os.MkdirAll(dir+"/prompts/git", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir+"/prompts/git/commit.md", []byte("Commit"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir+"/prompts/git/commit.presets.yaml", []byte("short:\n  STYLE: short\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := NewFakeLocationResolver()
+	resolver.Locations = []PromptLocation{{Type: "project-local", Path: dir + "/prompts"}}
+	manager := NewDefaultManager(fs, resolver)
+	manager.Trash = &Trash{Filesystem: fs, Dir: dir + "/trash", Now: time.Now}
+
+	if err := manager.Delete("git/commit"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := manager.Get("git/commit"); !errors.Is(err, ErrPromptNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrPromptNotFound", err)
+	}
+	if _, err := os.Stat(dir + "/prompts/git/commit.presets.yaml"); !os.IsNotExist(err) {
+		t.Errorf("Expected the presets to be removed with the prompt, got %v", err)
+	}
+
+	// Restoring recreates the directory the prompt was in
+	if err := os.Remove(dir + "/prompts/git"); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := manager.Restore("project-local:git/commit")
+	if err != nil {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+	if restored.Path != dir+"/prompts/git/commit.md" || restored.Source != "project-local" {
+		t.Errorf("Restore() = %+v", restored)
+	}
+	if info, err := manager.Get("git/commit"); err != nil || info.Content != "Commit" {
+		t.Errorf("Get() after Restore() = %+v, %v", info, err)
+	}
+	if presets, _ := os.ReadFile(dir + "/prompts/git/commit.presets.yaml"); string(presets) != "short:\n  STYLE: short\n" {
+		t.Errorf("Expected the presets to be restored, got %q", presets)
+	}
+
+	if _, err := manager.Restore("git/commit"); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("Restore() of a restored prompt error = %v, want ErrNotInTrash", err)
+	}
+
+	// A restored prompt does not replace a prompt created in the meantime
+	manager.Delete("git/commit")
+	manager.Create("git/commit", "New commit", "project-local")
+	if _, err := manager.Restore("git/commit"); !errors.Is(err, ErrPromptExists) {
+		t.Errorf("Restore() over an existing prompt error = %v, want ErrPromptExists", err)
+	}
+	// Entries with a relative path can't be put back reliably
+	entry := `{"name": "old", "source": "directory", "path": "prompts/old.md", "content": "Old"}`
+	if err := os.WriteFile(dir+"/trash/20250101T000000.000000000Z-old.json", []byte(entry), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Restore("old"); err == nil || !strings.Contains(err.Error(), "not absolute") {
+		t.Errorf("Restore() of a relative path error = %v, want a refusal", err)
+	}
+}
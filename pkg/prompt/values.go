@@ -0,0 +1,177 @@
+package prompt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatValue renders a placeholder value as text. Strings are used as is,
+// lists of scalars become one item per line, and maps and nested lists are
+// written as YAML; nil renders as the empty string
+func FormatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, "\n")
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if !isScalar(item) {
+				return formatYAML(v)
+			}
+			items = append(items, FormatValue(item))
+		}
+		return strings.Join(items, "\n")
+	case map[string]any:
+		return formatYAML(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	if isScalar(value) {
+		return fmt.Sprint(value)
+	}
+	return formatYAML(value)
+}
+
+// DecodeValues decodes a YAML mapping of placeholder values. Lists and
+// mappings stay structured and booleans become bool; other scalars such as
+// numbers keep their text exactly as written, so 1.10 is not turned into 1.1
+func DecodeValues(data []byte) (map[string]any, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return make(map[string]any), nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected NAME: value pairs", root.Line)
+	}
+	values, _ := decodeNode(root).(map[string]any)
+	return values, nil
+}
+
+// decodeNode converts a YAML node into a string, bool, nil, []any or map[string]any
+func decodeNode(node *yaml.Node) any {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return decodeNode(node.Alias)
+	case yaml.SequenceNode:
+		items := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			items = append(items, decodeNode(item))
+		}
+		return items
+	case yaml.MappingNode:
+		values := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			values[node.Content[i].Value] = decodeNode(node.Content[i+1])
+		}
+		return values
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!null":
+			return nil
+		case "!!bool":
+			if b, err := strconv.ParseBool(node.Value); err == nil {
+				return b
+			}
+		}
+		return node.Value
+	}
+	return nil
+}
+
+// FormatValues formats every value of a structured value map
+func FormatValues(values map[string]any) map[string]string {
+	formatted := make(map[string]string, len(values))
+	for name, value := range values {
+		formatted[name] = FormatValue(value)
+	}
+	return formatted
+}
+
+// StringValues converts plain string values into a structured value map
+func StringValues(values map[string]string) map[string]any {
+	converted := make(map[string]any, len(values))
+	for name, value := range values {
+		converted[name] = value
+	}
+	return converted
+}
+
+// isScalar reports whether value is a string, number or boolean
+func isScalar(value any) bool {
+	switch value.(type) {
+	case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
+
+// isTruthy reports whether a value counts as true in conditionals. Strings must
+// be non-blank and not parse as a false boolean such as "false" or "0"; lists
+// and maps must not be empty
+func isTruthy(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return false
+		}
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+		return true
+	case []any:
+		return len(v) > 0
+	case []string:
+		return len(v) > 0
+	case map[string]any:
+		return len(v) > 0
+	}
+	return isTruthy(FormatValue(value))
+}
+
+// listItems returns the items of a list value; a string value is split into its non-blank lines
+func listItems(value any) []any {
+	switch v := value.(type) {
+	case []any:
+		return v
+	case []string:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return items
+	case nil:
+		return nil
+	}
+	var items []any
+	for _, line := range splitLines(FormatValue(value)) {
+		items = append(items, line)
+	}
+	return items
+}
+
+// formatYAML writes a structured value as YAML with sorted keys and without a trailing newline
+func formatYAML(value any) string {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimRight(string(data), "\n")
+}
@@ -0,0 +1,83 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{"nil", nil, ""},
+		{"string", "hello", "hello"},
+		{"bool", true, "true"},
+		{"list", []any{"a.go", "b.go"}, "a.go\nb.go"},
+		{"map", map[string]any{"b": "2", "a": "1"}, "a: \"1\"\nb: \"2\""},
+		{"list of maps", []any{map[string]any{"name": "x"}}, "- name: x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatValue(tt.value); got != tt.expected {
+				t.Errorf("FormatValue() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDecodeValues(t *testing.T) {
+	values, err := DecodeValues([]byte("FILES: [a.go, b.go]\nVERSION: 1.10\nSECURITY: false\nEMPTY:\nOWNER:\n  name: ada\n  teams: [core]\n"))
+	if err != nil {
+		t.Fatalf("DecodeValues() error = %v", err)
+	}
+
+	expected := map[string]any{
+		"FILES":    []any{"a.go", "b.go"},
+		"VERSION":  "1.10",
+		"SECURITY": false,
+		"EMPTY":    nil,
+		"OWNER":    map[string]any{"name": "ada", "teams": []any{"core"}},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("DecodeValues() = %#v, want %#v", values, expected)
+	}
+
+	if _, err := DecodeValues([]byte("- a\n- b\n")); err == nil {
+		t.Error("DecodeValues() expected error for a top-level list")
+	}
+}
+
+func TestStructuredValuesInEngines(t *testing.T) {
+	values := map[string]any{
+		"FILES": []any{"a.go", "b.go"},
+		"OWNER": map[string]any{"name": "ada"},
+	}
+
+	tests := []struct {
+		name     string
+		engine   Engine
+		content  string
+		expected string
+	}{
+		{"native list", NewNativeEngine(NewDefaultParser()), "${FILES|bullets}", "- a.go\n- b.go"},
+		{"native join", NewNativeEngine(NewDefaultParser()), "${FILES|join}", "a.go, b.go"},
+		{"gotemplate range", NewGoTemplateEngine(), "{{range .FILES}}[{{.}}]{{end}} {{.OWNER.name}} {{join .FILES \"+\"}}", "[a.go][b.go] ada a.go+b.go"},
+		{"mustache list", NewMustacheEngine(), "{{#FILES}}[{{.}}]{{/FILES}}", "[a.go][b.go]"},
+		{"mustache map", NewMustacheEngine(), "{{#OWNER}}{{name}}{{/OWNER}} {{OWNER.name}}", "ada ada"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.engine.Render(tt.content, values)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Render() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}